@@ -0,0 +1,260 @@
+package align
+
+import (
+	"errors"
+	"math"
+)
+
+/*
+	Pure Go global aligner (Needleman-Wunsch with Gotoh affine
+	gaps). Scoring and statistics mimic EMBOSS needle so that
+	similarity values can be compared between both tools.
+*/
+
+// Default settings (identical to EMBOSS needle)
+const (
+	D_MATRIX    string  = BLOSUM62
+	D_GAPOPEN   float64 = 10.0
+	D_GAPEXTEND float64 = 0.5
+	D_ENDOPEN   float64 = 10.0
+	D_ENDEXTEND float64 = 0.5
+	D_ENDWEIGHT bool    = false
+)
+
+// Traceback states
+const (
+	stateM byte = iota
+	stateX      // Residue of A aligned to a gap
+	stateY      // Residue of B aligned to a gap
+)
+
+// Alignment parameters
+type Param struct {
+	Matrix    string
+	GapOpen   float64
+	GapExtend float64
+	EndWeight bool
+	EndOpen   float64
+	EndExtend float64
+}
+
+func NewParam() *Param {
+	return &Param{
+		Matrix:    D_MATRIX,
+		GapOpen:   D_GAPOPEN,
+		GapExtend: D_GAPEXTEND,
+		EndWeight: D_ENDWEIGHT,
+		EndOpen:   D_ENDOPEN,
+		EndExtend: D_ENDEXTEND,
+	}
+}
+
+// Alignment result
+type Result struct {
+	Length int
+	Nident int
+	Nsimil int
+	Ngap   int
+	Score  float64
+	AlignA string
+	AlignB string
+}
+
+// GETTERS (same as go-needle results)
+func (r *Result) GetSimilarityPct() float64 {
+	return float64(r.Nsimil) / float64(r.Length) * 100.0
+}
+func (r *Result) GetIdentityPct() float64 {
+	return float64(r.Nident) / float64(r.Length) * 100.0
+}
+func (r *Result) GetGapPct() float64 {
+	return float64(r.Ngap) / float64(r.Length) * 100.0
+}
+func (r *Result) GetScore() float64 {
+	return r.Score
+}
+func (r *Result) GetLength() int {
+	return r.Length
+}
+func (r *Result) GetAlignedSeqA() string {
+	return r.AlignA
+}
+func (r *Result) GetAlignedSeqB() string {
+	return r.AlignB
+}
+
+// Return the end gap penalties (open, extend)
+func (p *Param) endPenalties() (float64, float64) {
+	if p.EndWeight {
+		return p.EndOpen, p.EndExtend
+	}
+	return 0.0, 0.0
+}
+
+// Compute the global alignment of sequences a and b
+func Align(a, b []byte, p *Param) (*Result, error) {
+	n := len(a)
+	m := len(b)
+	if n == 0 || m == 0 {
+		return nil, errors.New("[Align]: Cannot align an empty sequence.")
+	}
+
+	mat, err := GetMatrix(p.Matrix)
+	if err != nil {
+		return nil, err
+	}
+	eo, ee := p.endPenalties()
+	inf := math.Inf(-1)
+
+	// Score rows (previous and current) for the three states
+	pM := make([]float64, m+1)
+	pX := make([]float64, m+1)
+	pY := make([]float64, m+1)
+	cM := make([]float64, m+1)
+	cX := make([]float64, m+1)
+	cY := make([]float64, m+1)
+
+	// Traceback matrix: 2 bits per state (M, X and Y)
+	tb := make([][]byte, n+1)
+	for i := range tb {
+		tb[i] = make([]byte, m+1)
+	}
+
+	// First row: only leading gaps in A
+	pM[0] = 0.0
+	pX[0] = inf
+	pY[0] = inf
+	for j := 1; j <= m; j++ {
+		pM[j] = inf
+		pX[j] = inf
+		pY[j], tb[0][j] = bestGap(pM[j-1], pX[j-1], pY[j-1], eo, ee, stateX, stateY)
+		tb[0][j] <<= 4
+	}
+
+	for i := 1; i <= n; i++ {
+		cM[0] = inf
+		cY[0] = inf
+		cX[0], tb[i][0] = bestGap(pM[0], pY[0], pX[0], eo, ee, stateY, stateX)
+		tb[i][0] <<= 2
+
+		// Gap penalties of the current row
+		yo, ye := p.GapOpen, p.GapExtend
+		if i == n {
+			yo, ye = eo, ee
+		}
+
+		for j := 1; j <= m; j++ {
+			var tM, tX, tY byte
+
+			// Match state
+			cM[j], tM = bestOf(pM[j-1], pX[j-1], pY[j-1])
+			cM[j] += float64(mat.Score(a[i-1], b[j-1]))
+
+			// Gap in B (vertical move)
+			xo, xe := p.GapOpen, p.GapExtend
+			if j == m {
+				xo, xe = eo, ee
+			}
+			cX[j], tX = bestGap(pM[j], pY[j], pX[j], xo, xe, stateY, stateX)
+
+			// Gap in A (horizontal move)
+			cY[j], tY = bestGap(cM[j-1], cX[j-1], cY[j-1], yo, ye, stateX, stateY)
+
+			tb[i][j] = tM | tX<<2 | tY<<4
+		}
+
+		pM, cM = cM, pM
+		pX, cX = cX, pX
+		pY, cY = cY, pY
+	}
+
+	// Best final state
+	var r Result
+	var state byte
+	r.Score, state = bestOf(pM[m], pX[m], pY[m])
+
+	// Traceback
+	alA := make([]byte, 0, n+m)
+	alB := make([]byte, 0, n+m)
+	i, j := n, m
+	for i > 0 || j > 0 {
+		switch state {
+		case stateM:
+			state = tb[i][j] & 3
+			i--
+			j--
+			alA = append(alA, a[i])
+			alB = append(alB, b[j])
+		case stateX:
+			state = (tb[i][j] >> 2) & 3
+			i--
+			alA = append(alA, a[i])
+			alB = append(alB, '-')
+		case stateY:
+			state = (tb[i][j] >> 4) & 3
+			j--
+			alA = append(alA, '-')
+			alB = append(alB, b[j])
+		}
+	}
+	reverse(alA)
+	reverse(alB)
+
+	// Compute statistics
+	r.Length = len(alA)
+	for k := range alA {
+		if alA[k] == '-' || alB[k] == '-' {
+			r.Ngap++
+			continue
+		}
+		if upper(alA[k]) == upper(alB[k]) {
+			r.Nident++
+		}
+		if mat.Score(alA[k], alB[k]) > 0 {
+			r.Nsimil++
+		}
+	}
+	r.AlignA = string(alA)
+	r.AlignB = string(alB)
+
+	return &r, nil
+}
+
+// Return the best score among the three states (M > X > Y on ties)
+func bestOf(m, x, y float64) (float64, byte) {
+	best, state := m, stateM
+	if x > best {
+		best, state = x, stateX
+	}
+	if y > best {
+		best, state = y, stateY
+	}
+	return best, state
+}
+
+// Return the best score to enter the gap state s, either by opening
+// it from the match state (m) or the other gap state (o, so), or by
+// extending it (e)
+func bestGap(m, o, e, open, extend float64, so, s byte) (float64, byte) {
+	best, state := m-open, stateM
+	if o-open > best {
+		best, state = o-open, so
+	}
+	if e-extend > best {
+		best, state = e-extend, s
+	}
+	return best, state
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+func reverse(s []byte) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
@@ -0,0 +1,165 @@
+package align
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hdevillers/go-needle"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+const (
+	FIXTURES  string  = "testdata/needle_fixtures.tsv"
+	TOLERANCE float64 = 0.5 // Tolerance on percentages
+)
+
+type fixture struct {
+	name      string
+	a, b      string
+	endweight bool // Penalize the end gaps
+	length    int
+	nident    int
+	nsimil    int
+	ngap      int
+	score     float64
+}
+
+func loadFixtures(t *testing.T) []fixture {
+	f, err := os.Open(FIXTURES)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	fixtures := make([]fixture, 0)
+	fs := bufio.NewScanner(f)
+	for fs.Scan() {
+		line := fs.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		elem := strings.Split(line, "\t")
+		if len(elem) != 9 || (elem[3] != "Y" && elem[3] != "N") {
+			t.Fatalf("Malformed fixture line: %s", line)
+		}
+		val := make([]int, 4)
+		for i := range val {
+			val[i], err = strconv.Atoi(elem[i+4])
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		score, err := strconv.ParseFloat(elem[8], 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fixtures = append(fixtures, fixture{elem[0], elem[1], elem[2], elem[3] == "Y", val[0], val[1], val[2], val[3], score})
+	}
+
+	if len(fixtures) == 0 {
+		t.Fatalf("No fixture found in %s.", FIXTURES)
+	}
+
+	return fixtures
+}
+
+// Matrices must be symmetric
+func TestMatrixSymmetry(t *testing.T) {
+	res := []byte("ARNDCQEGHILKMFPSTWYVBZX*")
+	for name := range matrices {
+		m, err := GetMatrix(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range res {
+			for _, b := range res {
+				if m.Score(a, b) != m.Score(b, a) {
+					t.Errorf("Matrix %s is not symmetric for %c/%c.", name, a, b)
+				}
+			}
+		}
+	}
+}
+
+// Compare the aligner against the statistics stored from needle
+func TestAlignFixtures(t *testing.T) {
+	for _, f := range loadFixtures(t) {
+		p := NewParam()
+		p.EndWeight = f.endweight
+		r, err := Align([]byte(f.a), []byte(f.b), p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Length != f.length || r.Nident != f.nident || r.Nsimil != f.nsimil || r.Ngap != f.ngap {
+			t.Errorf("Alignment of %s: expected %d/%d/%d/%d (length/identity/similarity/gaps), found %d/%d/%d/%d.",
+				f.name, f.length, f.nident, f.nsimil, f.ngap, r.Length, r.Nident, r.Nsimil, r.Ngap)
+		}
+		if r.Score != f.score {
+			t.Errorf("Alignment of %s: expected a score of %.1f, found %.1f.", f.name, f.score, r.Score)
+		}
+	}
+}
+
+// Edge cases of the statistics (end gaps, case)
+func TestAlignCases(t *testing.T) {
+	cases := []fixture{
+		{"identical", "MKVLA", "MKVLA", false, 5, 5, 5, 0, 0.0},
+		{"substitution", "MKVLA", "MRVLA", false, 5, 4, 5, 0, 0.0},
+		{"end gap", "MKVLA", "KVLA", false, 5, 4, 4, 1, 0.0},
+		{"internal gap", "MKVLAGT", "MKVGT", false, 7, 5, 5, 2, 0.0},
+		{"lower case", "mkvla", "MKVLA", false, 5, 5, 5, 0, 0.0},
+	}
+
+	p := NewParam()
+	for _, c := range cases {
+		r, err := Align([]byte(c.a), []byte(c.b), p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Length != c.length || r.Nident != c.nident || r.Nsimil != c.nsimil || r.Ngap != c.ngap {
+			t.Errorf("Alignment of %s/%s (%s): expected %d/%d/%d/%d (length/identity/similarity/gaps), found %d/%d/%d/%d.",
+				c.a, c.b, c.name, c.length, c.nident, c.nsimil, c.ngap, r.Length, r.Nident, r.Nsimil, r.Ngap)
+		}
+	}
+}
+
+// Cross-check the fixtures with the installed EMBOSS needle (if available)
+func TestAlignNeedle(t *testing.T) {
+	if _, err := exec.LookPath("needle"); err != nil {
+		t.Skip("needle is not available in the PATH.")
+	}
+
+	for _, f := range loadFixtures(t) {
+		p := NewParam()
+		p.EndWeight = f.endweight
+		r, err := Align([]byte(f.a), []byte(f.b), p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ndl := needle.NewNeedle(*seq.NewSeq("a"), *seq.NewSeq("b"))
+		ndl.Sqa.Sequence = []byte(f.a)
+		ndl.Sqb.Sequence = []byte(f.b)
+		ndl.Par.SetEndWeight(f.endweight)
+		err = ndl.Align()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ndl.Rst.GetLength() != f.length || ndl.Rst.GetIdentityCount() != f.nident || ndl.Rst.GetSimilarityCount() != f.nsimil || ndl.Rst.GetGapCount() != f.ngap {
+			t.Errorf("Fixture %s differs from needle: %d/%d/%d/%d (length/identity/similarity/gaps) vs %d/%d/%d/%d.", f.name,
+				f.length, f.nident, f.nsimil, f.ngap, ndl.Rst.GetLength(), ndl.Rst.GetIdentityCount(), ndl.Rst.GetSimilarityCount(), ndl.Rst.GetGapCount())
+		}
+		if math.Abs(ndl.Rst.GetScore()-f.score) > 0.05 {
+			t.Errorf("Score of the fixture %s differs from needle: %.1f vs %.1f.", f.name, f.score, ndl.Rst.GetScore())
+		}
+		if math.Abs(ndl.Rst.GetSimilarityPct()-r.GetSimilarityPct()) > TOLERANCE {
+			t.Errorf("Similarity of %s differs from needle: %.02f vs %.02f.", f.name, r.GetSimilarityPct(), ndl.Rst.GetSimilarityPct())
+		}
+	}
+}
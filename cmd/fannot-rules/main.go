@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hdevillers/go-fannot/fannot"
+)
+
+// Print an error message and exit with a failure status
+func check(e error) {
+	if e != nil {
+		fmt.Fprintln(os.Stderr, "Error:", e)
+		os.Exit(1)
+	}
+}
+
+// Print a usage error message and exit
+func usage(msg string) {
+	fmt.Fprintln(os.Stderr, "Error:", msg)
+	flag.Usage()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s check [options] <rules.json>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	if len(os.Args) < 2 || os.Args[1] != "check" {
+		usage("Unknown or missing command (must be check).")
+	}
+
+	// Parse the options of the command
+	flag.CommandLine.Init("check", flag.ExitOnError)
+	strict := flag.Bool("strict", false, "Consider warnings as errors.")
+	check(flag.CommandLine.Parse(os.Args[2:]))
+	if flag.NArg() == 0 {
+		usage("You must provide at least one rules file.")
+	}
+
+	failed := false
+	for _, file := range flag.Args() {
+		p, err := fannot.NewParamFromJson(file)
+		if err != nil {
+			fmt.Printf("%s: error: %s\n", file, err)
+			failed = true
+			continue
+		}
+
+		issues := p.Validate()
+		for _, is := range issues {
+			fmt.Printf("%s: %s\n", file, is)
+		}
+		if fannot.HasError(issues) || (*strict && len(issues) > 0) {
+			failed = true
+		} else if len(issues) == 0 {
+			fmt.Printf("%s: OK (%d rules, %d DB rule sets)\n", file, len(p.Rules), len(p.Db_rules))
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
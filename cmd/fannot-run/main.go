@@ -2,50 +2,198 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
 
+	"github.com/hdevillers/go-fannot/align"
 	"github.com/hdevillers/go-fannot/fannot"
+	"github.com/hdevillers/go-fannot/flatfile"
 )
 
+// Print a usage error message and exit
+func usage(msg string) {
+	fmt.Fprintln(os.Stderr, "Error:", msg)
+	flag.Usage()
+	os.Exit(2)
+}
+
 func main() {
+	// Errors are returned to main so that the deferred cleanups of
+	// run are executed before exiting
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
 	query := flag.String("query", "", "Input query fasta file.")
 	refdb := flag.String("refdb", "", "List of reference DB (coma separator).")
 	dirdb := flag.String("dirdb", "", "Sub-directory that contains the reference DBs.")
 	rules := flag.String("rules", "", "JSON file containing similarity levels.")
 	ipsin := flag.String("ips", "", "InterProScan output predictions (TSV format).")
 	threads := flag.Int("threads", 4, "Number of threads.")
+	search := flag.String("search", "blast", "Homology search tool: blast, diamond or mmseqs (batch mode).")
+	batch := flag.Bool("batch", false, "Run BLASTP once per reference DB over all queries.")
+	aligner := flag.String("aligner", "needle", "Global aligner: needle (EMBOSS) or native (built-in).")
+	matrix := flag.String("matrix", "BLOSUM62", "Substitution matrix of the native aligner (BLOSUM62, BLOSUM45 or PAM250).")
+	rbh := flag.Bool("rbh", false, "Copy gene names only from reciprocal best hits (the references are searched back against the queries).")
+	ckpt := flag.String("checkpoint", "", "Checkpoint file saved after each reference DB round (and periodically).")
+	ckptEvery := flag.Duration("checkpoint-every", 10*time.Minute, "Delay between two checkpoints within a reference DB round.")
+	resume := flag.Bool("resume", false, "Resume the run from the checkpoint file.")
+	format := flag.String("format", fannot.TSV_FORMAT, "Output format of the results: tsv, json or jsonl.")
+	gffin := flag.String("gff", "", "Input GFF3 file of the gene models (IDs must match the queries).")
+	gffout := flag.String("gff-out", "", "Output GFF3 file with the functional annotation.")
+	genome := flag.String("genome", "", "Input genome FASTA file of the gene models (see -gff).")
+	flatout := flag.String("flat-out", "", "Output flat file of the annotated genome (requires -gff and -genome).")
+	flatfmt := flag.String("flat-format", "genbank", "Format of the flat file: genbank or embl.")
+	organism := flag.String("organism", "", "Organism name of the annotated genome (flat file output).")
+	tblout := flag.String("tbl-out", "", "Output NCBI feature table (.tbl) of the gene models (requires -gff).")
+	tbldb := flag.String("tbl-db", fannot.D_TBL_DB, "Database tag of the transcript and protein IDs in the feature table.")
 	flag.Parse()
 
 	if *query == "" {
-		panic("You must provide an input query file.")
+		usage("You must provide an input query file.")
 	}
 	if *refdb == "" {
-		panic("You must provide at least one reference DB.")
+		usage("You must provide at least one reference DB.")
+	}
+	if *aligner != fannot.NEEDLE_ALIGNER && *aligner != fannot.NATIVE_ALIGNER {
+		usage("Unknown global aligner (must be needle or native).")
+	}
+	if *search != fannot.BLAST_SEARCH && *search != fannot.DIAMOND_SEARCH && *search != fannot.MMSEQS_SEARCH {
+		usage("Unknown homology search tool (must be blast, diamond or mmseqs).")
+	}
+	if _, err := align.GetMatrix(*matrix); err != nil {
+		usage(err.Error())
+	}
+	if *resume && *ckpt == "" {
+		usage("You must provide the checkpoint file to resume a run.")
+	}
+	if !fannot.IsResultFormat(*format) {
+		usage("Unknown output format (must be tsv, json or jsonl).")
+	}
+	if *gffout != "" && *gffin == "" {
+		usage("You must provide the input GFF3 file of the gene models.")
+	}
+	if *flatout != "" && (*gffin == "" || *genome == "") {
+		usage("You must provide the gene models (GFF3) and the genome (FASTA) to write a flat file.")
+	}
+	if *tblout != "" && *gffin == "" {
+		usage("You must provide the gene models (GFF3) to write a feature table.")
+	}
+	if !flatfile.IsFormat(*flatfmt) {
+		usage("Unknown flat file format (must be genbank or embl).")
 	}
 
 	// Initialize the functional annotation strucutre
-	fa := fannot.NewFannot(*query)
+	fa, err := fannot.NewFannot(*query)
+	if err != nil {
+		return err
+	}
+
+	// Setup the homology search tool
+	switch *search {
+	case fannot.BLAST_SEARCH:
+		if *batch {
+			bs := fannot.NewBatchBlastSearcher()
+			bs.Par.SetThreads(*threads)
+			fa.Searcher = bs
+		}
+	case fannot.DIAMOND_SEARCH:
+		ds := fannot.NewDiamondSearcher()
+		ds.Threads = *threads
+		fa.Searcher = ds
+	case fannot.MMSEQS_SEARCH:
+		ms := fannot.NewMmseqsSearcher()
+		ms.Threads = *threads
+		fa.Searcher = ms
+	}
+
+	// Setup the global aligner
+	if *aligner == fannot.NATIVE_ALIGNER {
+		na := fannot.NewNativeAligner()
+		na.Par.Matrix = *matrix
+		fa.Aligner = na
+	}
 
 	// Reset rules if a JSON is provided
 	if *rules != "" {
-		fa.FaPar = *fannot.NewParamFromJson(*rules)
+		par, err := fannot.NewParamFromJson(*rules)
+		if err != nil {
+			return err
+		}
+		issues := par.Validate()
+		for _, is := range issues {
+			fmt.Fprintln(os.Stderr, "Rules:", is)
+		}
+		if fannot.HasError(issues) {
+			return fmt.Errorf("Invalid rules file %s (see fannot-rules check).", *rules)
+		}
+		fa.SetParam(par)
+	}
+
+	// Build the search DB of the queries (reciprocal best hits)
+	if *rbh {
+		dir, err := ioutil.TempDir("", "fannot-queries-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		if err := fa.MakeQueryDB(dir, *search); err != nil {
+			return err
+		}
 	}
 
 	// Parse the list of reference DB
-	fa.GetDBs(*refdb, *dirdb)
+	if err := fa.GetDBs(*refdb, *dirdb); err != nil {
+		return err
+	}
+
+	// Restore the state of a previous run
+	if *resume {
+		if err := fa.LoadCheckpoint(*ckpt); err != nil {
+			return err
+		}
+	}
 
 	// Load ips if provided
 	if *ipsin != "" {
-		err := fa.Ips.LoadIpsData(*ipsin)
-		if err != nil {
-			panic(err)
+		if err := fa.Ips.LoadIpsData(*ipsin); err != nil {
+			return err
 		}
 	}
 
 REFDB:
 	for fa.NextDB() {
+		// List gene index that require a function
+		qis := make([]int, 0)
+		nq := 0 // Number of thrown queries
+		for i := 0; i < fa.NQueries; i++ {
+			if !fa.Finished[i] {
+				nq++
+			} else if fa.DBs[fa.DBi].OverWrite && fa.Results[i].Status == 1 {
+				// Try to overwrite the annotation
+				nq++
+			} else {
+				continue
+			}
+			// Skip queries already processed before resuming
+			if !fa.Checked[i] {
+				qis = append(qis, i)
+			}
+		}
+
+		// Search all queries at once (if supported by the search tool)
+		if err := fa.SearchBatch(qis); err != nil {
+			return err
+		}
+
 		// Create the channels for multithreading
 		queryChan := make(chan int)
-		threadChan := make(chan int)
+		threadChan := make(chan error)
 
 		// Launch parallel go routines
 		for i := 0; i < *threads; i++ {
@@ -53,22 +201,38 @@ REFDB:
 		}
 
 		// throw gene index that require a function
-		nq := 0 // Number of thrown queries
-		for i := 0; i < fa.NQueries; i++ {
-			if !fa.Finished[i] {
-				nq++
-				queryChan <- i
-			} else if fa.DBs[fa.DBi].OverWrite && fa.Results[i].Status == 1 {
-				// Try to overwrite the annotation
-				nq++
-				queryChan <- i
+		last := time.Now()
+		for _, i := range qis {
+			queryChan <- i
+
+			// Periodic checkpoint
+			if *ckpt != "" && time.Since(last) >= *ckptEvery {
+				if err := fa.SaveCheckpoint(*ckpt); err != nil {
+					return err
+				}
+				last = time.Now()
 			}
 		}
 		close(queryChan)
 
-		// Wait for all threads
+		// Wait for all threads (keep the first error)
+		var werr error
 		for i := 0; i < *threads; i++ {
-			<-threadChan
+			terr := <-threadChan
+			if werr == nil {
+				werr = terr
+			}
+		}
+
+		// End of round checkpoint (also saved on failure to keep
+		// the processed queries)
+		if *ckpt != "" {
+			if err := fa.SaveCheckpoint(*ckpt); err != nil {
+				return err
+			}
+		}
+		if werr != nil {
+			return werr
 		}
 
 		// If every sequence has a function, then stop
@@ -76,15 +240,35 @@ REFDB:
 			break REFDB
 		}
 	}
+	if err := fa.Err(); err != nil {
+		return err
+	}
 
 	// Complete with IPS annotation if provided
 	if *ipsin != "" {
 		fa.AddIpsAnnot()
 	}
 
-	// Printout the results
-	fannot.PrintFAResultsHeader()
-	for i := 0; i < fa.NQueries; i++ {
-		fa.Results[i].PrintFAResult(fa.Queries[i].Id)
+	// Remove duplicated gene names (see Dup_nam)
+	fa.DedupNames()
+
+	// Write the annotated gene models if requested
+	if *gffout != "" {
+		if err := fa.WriteGff(*gffin, *gffout); err != nil {
+			return err
+		}
+	}
+	if *flatout != "" {
+		if err := fa.WriteFlatFile(*genome, *gffin, *flatout, *flatfmt, *organism); err != nil {
+			return err
+		}
 	}
+	if *tblout != "" {
+		if err := fa.WriteTbl(*gffin, *tblout, *tbldb); err != nil {
+			return err
+		}
+	}
+
+	// Printout the results
+	return fa.WriteResults(os.Stdout, *format)
 }
@@ -2,10 +2,27 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
 
 	"github.com/hdevillers/go-fannot/refdb"
 )
 
+// Print an error message and exit with a failure status
+func check(e error) {
+	if e != nil {
+		fmt.Fprintln(os.Stderr, "Error:", e)
+		os.Exit(1)
+	}
+}
+
+// Print a usage error message and exit
+func usage(msg string) {
+	fmt.Fprintln(os.Stderr, "Error:", msg)
+	flag.Usage()
+	os.Exit(2)
+}
+
 func main() {
 	id := flag.String("id", "", "Id of the reference database or path of the config.json file.")
 	dir := flag.String("dir", ".", "Directory that contain databases.")
@@ -13,11 +30,12 @@ func main() {
 
 	// Check input values and find the JSON file
 	if *id == "" {
-		panic("You must provide the ID of the queried reference database or its config.json file.")
+		usage("You must provide the ID of the queried reference database or its config.json file.")
 	}
 
 	// Load the refdb object
-	rdb := refdb.FindRefDB(*id, *dir)
+	rdb, err := refdb.FindRefDB(*id, *dir)
+	check(err)
 
 	// Print-out the info
 	rdb.PrintInfoHeader()
@@ -1,28 +1,40 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"os"
 
 	"github.com/hdevillers/go-fannot/swiss"
 )
 
+// Print an error message and exit with a failure status
 func check(e error) {
 	if e != nil {
-		panic(e)
+		fmt.Fprintln(os.Stderr, "Error:", e)
+		os.Exit(1)
 	}
 }
 
+// Print a usage error message and exit
+func usage(msg string) {
+	fmt.Fprintln(os.Stderr, "Error:", msg)
+	flag.Usage()
+	os.Exit(2)
+}
+
 func main() {
 	input := flag.String("i", "", "Input SwissProt data file.")
 	flag.Parse()
 
 	if *input == "" {
-		panic("You must provide a SwissProt data file.")
+		usage("You must provide a SwissProt data file.")
 	}
 
 	// Create a reader
-	swr := swiss.NewReader(*input)
+	swr, err := swiss.NewReader(*input)
+	check(err)
 	defer swr.Close()
 
 	// Count entry
@@ -30,10 +42,11 @@ func main() {
 	for swr.Next() {
 		cnt++
 	}
+	check(swr.Err())
 
 	// Display the number of entry
 	if cnt == 0 {
-		panic("No entry found, please check the input file format.")
+		check(errors.New("No entry found, please check the input file format."))
 	} else if cnt == 1 {
 		fmt.Println("Found 1 SwissProt entry in", *input)
 	} else {
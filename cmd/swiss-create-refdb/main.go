@@ -2,10 +2,28 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"strings"
 
 	"github.com/hdevillers/go-fannot/refdb"
 )
 
+// Print an error message and exit with a failure status
+func check(e error) {
+	if e != nil {
+		fmt.Fprintln(os.Stderr, "Error:", e)
+		os.Exit(1)
+	}
+}
+
+// Print a usage error message and exit
+func usage(msg string) {
+	fmt.Fprintln(os.Stderr, "Error:", msg)
+	flag.Usage()
+	os.Exit(2)
+}
+
 func main() {
 	input := flag.String("input", "", "Input swissProt data file.")
 	name := flag.String("id", "", "Name of the reference database.")
@@ -15,21 +33,32 @@ func main() {
 	unre := flag.Bool("unreviewed", false, "Indicate if annotation are unreviewed (from TrEmbl).")
 	gn := flag.Bool("gene-name", false, "Indicate if gene name can be transfered in query features.")
 	desc := flag.String("desc", "No description", "Database description.")
+	backends := flag.String("backends", "blast", "Search backends to build (coma separator): blast, diamond, mmseqs.")
 	flag.Parse()
 
 	if *input == "" {
-		panic("You must provide a SwissProt data file.")
+		usage("You must provide a SwissProt data file.")
 	}
 	if *name == "" {
-		panic("You must provide a name for the new reference database.")
+		usage("You must provide a name for the new reference database.")
+	}
+
+	// Check the requested backends
+	bs := strings.Split(*backends, ",")
+	for _, b := range bs {
+		if !refdb.IsBackend(b) {
+			usage(fmt.Sprintf("Unknown search backend: %s.", b))
+		}
 	}
 
 	// Create the refdb object
-	rdb := refdb.NewRefdb(*outdir, *name, *input, *desc, *equal, *ow, !*unre, *gn)
+	rdb, err := refdb.NewRefdb(*outdir, *name, *input, *desc, *equal, *ow, !*unre, *gn)
+	check(err)
+	rdb.Backends = bs
 
 	// Load the data
-	rdb.LoadSource()
+	check(rdb.LoadSource())
 
 	// Save the json config
-	rdb.WriteJson()
+	check(rdb.WriteJson())
 }
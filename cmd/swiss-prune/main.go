@@ -3,17 +3,27 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"regexp"
 
 	"github.com/hdevillers/go-fannot/swiss"
 )
 
+// Print an error message and exit with a failure status
 func check(e error) {
 	if e != nil {
-		panic(e)
+		fmt.Fprintln(os.Stderr, "Error:", e)
+		os.Exit(1)
 	}
 }
 
+// Print a usage error message and exit
+func usage(msg string) {
+	fmt.Fprintln(os.Stderr, "Error:", msg)
+	flag.Usage()
+	os.Exit(2)
+}
+
 func main() {
 	input := flag.String("i", "", "Input SwissProt data file.")
 	output := flag.String("o", "", "Output pruned SwissPort data file.")
@@ -23,20 +33,19 @@ func main() {
 	flag.Parse()
 
 	if *input == "" {
-		panic("You must provide a SwissProt data file.")
+		usage("You must provide a SwissProt data file.")
 	}
 
 	if *output == "" {
-		panic("You must provide an output file name.")
+		usage("You must provide an output file name.")
 	}
 
-	swr := swiss.NewReader(*input)
-	swr.PanicOnError()
+	swr, err := swiss.NewReader(*input)
+	check(err)
 	defer swr.Close()
 
-	sww := swiss.NewWriter(*output)
-	sww.PanicOnError()
-	defer sww.Close()
+	sww, err := swiss.NewWriter(*output)
+	check(err)
 
 	tot := 0
 	kpt := 0
@@ -45,7 +54,8 @@ func main() {
 	reMeth := regexp.MustCompile(`^M`)
 
 	for swr.Next() {
-		e := swr.Parse()
+		e, err := swr.Parse()
+		check(err)
 		tot++
 
 		if *pmeth {
@@ -66,10 +76,11 @@ func main() {
 			}
 		}
 		kpt++
-		sww.WriteStrings(swr.GetData())
-		sww.WriteEntryEnd()
-		sww.PanicOnError()
+		check(sww.WriteStrings(swr.GetData()))
+		check(sww.WriteEntryEnd())
 	}
+	check(swr.Err())
+	check(sww.Close())
 
 	fmt.Println("Scan", tot, "entries and kept", kpt, "ones.")
 }
@@ -3,16 +3,26 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 
 	"github.com/hdevillers/go-fannot/swiss"
 )
 
+// Print an error message and exit with a failure status
 func check(e error) {
 	if e != nil {
-		panic(e)
+		fmt.Fprintln(os.Stderr, "Error:", e)
+		os.Exit(1)
 	}
 }
 
+// Print a usage error message and exit
+func usage(msg string) {
+	fmt.Fprintln(os.Stderr, "Error:", msg)
+	flag.Usage()
+	os.Exit(2)
+}
+
 func main() {
 	input := flag.String("i", "", "Input SwissProt data file.")
 	output := flag.String("o", "", "Output file basename.")
@@ -21,19 +31,19 @@ func main() {
 	flag.Parse()
 
 	if *input == "" {
-		panic("You must provide a SwissProt data file.")
+		usage("You must provide a SwissProt data file.")
 	}
 
 	if *output == "" {
-		panic("You must provide an output file basename.")
+		usage("You must provide an output file basename.")
 	}
 
 	if *nsplit < 2 {
-		panic("The number of file division must be greater than 1.")
+		usage("The number of file division must be greater than 1.")
 	}
 
-	swr := swiss.NewReader(*input)
-	swr.PanicOnError()
+	swr, err := swiss.NewReader(*input)
+	check(err)
 	defer swr.Close()
 
 	writers := make([]*swiss.Writer, *nsplit)
@@ -43,20 +53,23 @@ func main() {
 	}
 
 	for i := 0; i < *nsplit; i++ {
-		writers[i] = swiss.NewWriter(*output + fmt.Sprintf("%03d", i) + fileExt)
-		writers[i].PanicOnError()
-		defer writers[i].Close()
+		writers[i], err = swiss.NewWriter(*output + fmt.Sprintf("%03d", i) + fileExt)
+		check(err)
 	}
 
 	wi := 0
 	for swr.Next() {
-		writers[wi].WriteStrings(swr.GetData())
-		writers[wi].WriteEntryEnd()
-		writers[wi].PanicOnError()
+		check(writers[wi].WriteStrings(swr.GetData()))
+		check(writers[wi].WriteEntryEnd())
 		wi++
 		if wi == *nsplit {
 			wi = 0
 		}
 	}
+	check(swr.Err())
+
+	for i := 0; i < *nsplit; i++ {
+		check(writers[i].Close())
+	}
 
 }
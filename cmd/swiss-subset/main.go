@@ -22,24 +22,36 @@ type SubsetWriter struct {
 	Writer *swiss.Writer
 }
 
-func NewSubsetWriter(o string) *SubsetWriter {
-	return &SubsetWriter{swiss.NewWriter(o)}
+func NewSubsetWriter(o string) (*SubsetWriter, error) {
+	w, err := swiss.NewWriter(o)
+	if err != nil {
+		return nil, err
+	}
+	return &SubsetWriter{w}, nil
 }
 
+// Print an error message and exit with a failure status
 func check(e error) {
 	if e != nil {
-		panic(e)
+		fmt.Fprintln(os.Stderr, "Error:", e)
+		os.Exit(1)
 	}
 }
 
+// Print a usage error message and exit
+func usage(msg string) {
+	fmt.Fprintln(os.Stderr, "Error:", msg)
+	flag.Usage()
+	os.Exit(2)
+}
+
 // Recorder routine
 func (sww *SubsetWriter) recordEntry(ec chan *[]string, re chan int) {
 	nrec := 0
 
 	for e := range ec {
-		sww.Writer.WriteStrings(e)
-		sww.Writer.WriteEntryEnd()
-		sww.Writer.PanicOnError()
+		check(sww.Writer.WriteStrings(e))
+		check(sww.Writer.WriteEntryEnd())
 		nrec++
 	}
 
@@ -49,15 +61,16 @@ func (sww *SubsetWriter) recordEntry(ec chan *[]string, re chan int) {
 
 func (s *Subset) parseFile(ec chan *[]string, th chan int, in string) {
 	// Create a reader
-	swr := swiss.NewReader(in)
-	swr.PanicOnError()
+	swr, err := swiss.NewReader(in)
+	check(err)
 	defer swr.Close()
 
 	ntot := 0
 
 	for swr.Next() {
 		// Parse the entry
-		e := swr.LightParse()
+		e, err := swr.LightParse()
+		check(err)
 		ntot++
 
 		if e.Length < s.Lmin {
@@ -65,25 +78,33 @@ func (s *Subset) parseFile(ec chan *[]string, th chan int, in string) {
 		}
 
 		if s.Eskip != "" {
-			if e.TestEvidence(s.Eskip) {
+			test, err := e.TestEvidence(s.Eskip)
+			check(err)
+			if test {
 				continue
 			}
 		}
 
 		if s.Tskip != "" {
-			if e.TestTaxonomy(s.Tskip) {
+			test, err := e.TestTaxonomy(s.Tskip)
+			check(err)
+			if test {
 				continue
 			}
 		}
 
 		if s.Ekeep != "" {
-			if !e.TestEvidence(s.Ekeep) {
+			test, err := e.TestEvidence(s.Ekeep)
+			check(err)
+			if !test {
 				continue
 			}
 		}
 
 		if s.Tkeep != "" {
-			if !e.TestTaxonomy(s.Tkeep) {
+			test, err := e.TestTaxonomy(s.Tkeep)
+			check(err)
+			if !test {
 				continue
 			}
 		}
@@ -95,6 +116,8 @@ func (s *Subset) parseFile(ec chan *[]string, th chan int, in string) {
 		ec <- &tmp
 	}
 
+	check(swr.Err())
+
 	// Throw the number of scanned entries
 	th <- ntot
 }
@@ -110,15 +133,15 @@ func main() {
 	flag.Parse()
 
 	if *input == "" {
-		panic("You must provide a SwissProt data file.")
+		usage("You must provide a SwissProt data file.")
 	}
 
 	if *output == "" {
-		panic("You must provide an output file name.")
+		usage("You must provide an output file name.")
 	}
 
 	if *ekeep == "" && *eskip == "" && *tkeep == "" && *tskip == "" {
-		panic("You must provide at least one keep/skip instruction.")
+		usage("You must provide at least one keep/skip instruction.")
 	}
 
 	// Check if input is a single file or a base name for multiple files
@@ -126,11 +149,9 @@ func main() {
 	if _, err := os.Stat(*input); errors.Is(err, os.ErrNotExist) {
 		// This is probably not a single file, then look for multiple files
 		files, err = filepath.Glob(*input + "*")
-		if err != nil {
-			panic(err)
-		}
+		check(err)
 		if files == nil {
-			panic("Failed to found files from the provided pattern.")
+			check(errors.New("Failed to found files from the provided pattern."))
 		}
 	} else {
 		files = append(files, *input)
@@ -142,9 +163,8 @@ func main() {
 	recordChan := make(chan int)
 
 	// Initialze output writer
-	sww := NewSubsetWriter(*output)
-	sww.Writer.PanicOnError()
-	defer sww.Writer.Close()
+	sww, err := NewSubsetWriter(*output)
+	check(err)
 
 	// Launch the recording routine
 	go sww.recordEntry(entryChan, recordChan)
@@ -166,6 +186,7 @@ func main() {
 
 	// Wait for the recorder
 	kpt := <-recordChan
+	check(sww.Writer.Close())
 
 	fmt.Println("Scan", tot, "entries and kept", kpt, "ones.")
 }
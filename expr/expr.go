@@ -0,0 +1,252 @@
+package expr
+
+/*
+	Small boolean expression language used in the annotation
+	rules. Expressions are type-checked when compiled, hence a
+	compiled expression cannot fail on values of the declared
+	types.
+
+	Examples:
+		sim >= 90 && genus == "Saccharomyces"
+		not reviewed or "Fungi" in lineage
+		organism =~ "^Candida " and evalue < 1e-30
+*/
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Value types
+type Type int
+
+const (
+	NUMBER Type = iota
+	STRING
+	BOOL
+	LIST // List of strings
+)
+
+func (t Type) String() string {
+	switch t {
+	case NUMBER:
+		return "number"
+	case STRING:
+		return "string"
+	case BOOL:
+		return "boolean"
+	case LIST:
+		return "list"
+	}
+	return "unknown"
+}
+
+// Declared variables and their type
+type Env map[string]Type
+
+// Variable values: float64 (NUMBER), string (STRING), bool (BOOL)
+// and []string (LIST)
+type Vars map[string]interface{}
+
+// Compilation error with the position (1-based) in the expression
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("at position %d: %s", e.Pos, e.Msg)
+}
+
+func newError(pos int, format string, a ...interface{}) *Error {
+	return &Error{pos, fmt.Sprintf(format, a...)}
+}
+
+// Compiled boolean expression
+type Expr struct {
+	src  string
+	root node
+	env  Env
+}
+
+// Parse and type-check a boolean expression
+func Compile(src string, env Env) (*Expr, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{toks: toks, env: env}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if root.typ() != BOOL {
+		return nil, newError(1, "the expression must be a boolean, found a %s", root.typ())
+	}
+	return &Expr{src, root, env}, nil
+}
+
+// Return the source of the expression
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Evaluate the expression, all the variables used in the
+// expression must be set with the declared type
+func (e *Expr) Eval(v Vars) (bool, error) {
+	r, err := e.root.eval(v)
+	if err != nil {
+		return false, err
+	}
+	return r.(bool), nil
+}
+
+// AST nodes
+type node interface {
+	typ() Type
+	eval(v Vars) (interface{}, error)
+}
+
+type literal struct {
+	t   Type
+	val interface{}
+}
+
+func (n *literal) typ() Type                        { return n.t }
+func (n *literal) eval(v Vars) (interface{}, error) { return n.val, nil }
+
+type variable struct {
+	t    Type
+	name string
+}
+
+func (n *variable) typ() Type { return n.t }
+func (n *variable) eval(v Vars) (interface{}, error) {
+	val, ok := v[n.name]
+	if !ok {
+		return nil, fmt.Errorf("Variable %s is not set.", n.name)
+	}
+	ok = false
+	switch n.t {
+	case NUMBER:
+		_, ok = val.(float64)
+	case STRING:
+		_, ok = val.(string)
+	case BOOL:
+		_, ok = val.(bool)
+	case LIST:
+		_, ok = val.([]string)
+	}
+	if !ok {
+		return nil, fmt.Errorf("Variable %s must be a %s (found %T).", n.name, n.t, val)
+	}
+	return val, nil
+}
+
+type not struct {
+	x node
+}
+
+func (n *not) typ() Type { return BOOL }
+func (n *not) eval(v Vars) (interface{}, error) {
+	x, err := n.x.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	return !x.(bool), nil
+}
+
+// Logical operators (short-circuit evaluation)
+type logical struct {
+	and  bool
+	l, r node
+}
+
+func (n *logical) typ() Type { return BOOL }
+func (n *logical) eval(v Vars) (interface{}, error) {
+	l, err := n.l.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	if l.(bool) != n.and {
+		// false && ... or true || ...
+		return l, nil
+	}
+	return n.r.eval(v)
+}
+
+type compare struct {
+	op   string
+	l, r node
+}
+
+func (n *compare) typ() Type { return BOOL }
+func (n *compare) eval(v Vars) (interface{}, error) {
+	l, err := n.l.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.r.eval(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.l.typ() == NUMBER {
+		a, b := l.(float64), r.(float64)
+		switch n.op {
+		case "<":
+			return a < b, nil
+		case "<=":
+			return a <= b, nil
+		case ">":
+			return a > b, nil
+		case ">=":
+			return a >= b, nil
+		}
+	}
+	switch n.op {
+	case "==":
+		return l == r, nil
+	case "!=":
+		return l != r, nil
+	}
+	return nil, fmt.Errorf("Unexpected operator %s.", n.op)
+}
+
+// String matching a regular expression (compiled with the expression)
+type match struct {
+	x  node
+	re *regexp.Regexp
+}
+
+func (n *match) typ() Type { return BOOL }
+func (n *match) eval(v Vars) (interface{}, error) {
+	x, err := n.x.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	return n.re.MatchString(x.(string)), nil
+}
+
+// String in a list
+type in struct {
+	x, list node
+}
+
+func (n *in) typ() Type { return BOOL }
+func (n *in) eval(v Vars) (interface{}, error) {
+	x, err := n.x.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	l, err := n.list.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range l.([]string) {
+		if s == x.(string) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,91 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+)
+
+var testEnv = Env{
+	"sim":      NUMBER,
+	"evalue":   NUMBER,
+	"genus":    STRING,
+	"organism": STRING,
+	"lineage":  LIST,
+	"reviewed": BOOL,
+}
+
+var testVars = Vars{
+	"sim":      92.5,
+	"evalue":   1e-40,
+	"genus":    "Saccharomyces",
+	"organism": "Saccharomyces cerevisiae",
+	"lineage":  []string{"Eukaryota", "Fungi", "Saccharomycetaceae", "Saccharomyces"},
+	"reviewed": true,
+}
+
+func TestEval(t *testing.T) {
+	tests := map[string]bool{
+		`sim >= 90 && genus == "Saccharomyces"`:            true,
+		`sim >= 95 || genus == "Candida"`:                  false,
+		`not reviewed or "Fungi" in lineage`:               true,
+		`organism =~ "^Saccharomyces " and evalue < 1e-30`: true,
+		`!(sim > 90) || evalue > 1e-10`:                    false,
+		`'Metazoa' in lineage`:                             false,
+		`reviewed == true and genus != 'Candida'`:          true,
+		`sim > 10 and sim < 50 or sim > 90`:                true,
+	}
+	for src, exp := range tests {
+		e, err := Compile(src, testEnv)
+		if err != nil {
+			t.Errorf("Failed to compile %s: %v.", src, err)
+			continue
+		}
+		r, err := e.Eval(testVars)
+		if err != nil {
+			t.Errorf("Failed to evaluate %s: %v.", src, err)
+		}
+		if r != exp {
+			t.Errorf("Expected %t for %s, found %t.", exp, src, r)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := map[string]int{
+		`sim >= 90 && gnus == "Saccharomyces"`: 14, // Unknown variable
+		`sim >= "90"`:                          5,  // Type mismatch
+		`sim >= 90 &&`:                         13, // Missing operand
+		`(sim >= 90`:                           11, // Missing parenthesis
+		`genus == "Sacch`:                      10, // Unterminated string
+		`organism =~ "[a-"`:                    13, // Invalid regex
+		`sim`:                                  1,  // Not a boolean
+		`sim >= 90 # 1`:                        11, // Unexpected character
+		`lineage in genus`:                     9,  // Wrong operands
+	}
+	for src, pos := range tests {
+		_, err := Compile(src, testEnv)
+		var cerr *Error
+		if !errors.As(err, &cerr) {
+			t.Errorf("Expected a compilation error for %s, found %v.", src, err)
+			continue
+		}
+		if cerr.Pos != pos {
+			t.Errorf("Expected an error at position %d for %s, found %v.", pos, src, cerr)
+		}
+	}
+}
+
+func TestEvalMissingVariable(t *testing.T) {
+	e, err := Compile(`sim > 90`, testEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = e.Eval(Vars{"sim": "high"})
+	if err == nil {
+		t.Error("Expected an error for a variable of the wrong type.")
+	}
+	_, err = e.Eval(Vars{})
+	if err == nil {
+		t.Error("Expected an error for a missing variable.")
+	}
+}
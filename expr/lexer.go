@@ -0,0 +1,118 @@
+package expr
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Token kinds
+const (
+	tEOF = iota
+	tNumber
+	tString
+	tIdent
+	tOp
+	tLParen
+	tRParen
+)
+
+type token struct {
+	kind int
+	text string  // Operator, identifier or unquoted string
+	num  float64 // Value of a number
+	pos  int     // 1-based position in the source
+}
+
+// Operators (longest first)
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!"}
+
+// Keywords equivalent to operators
+var keywords = map[string]string{
+	"and": "&&",
+	"or":  "||",
+	"not": "!",
+	"in":  "in",
+}
+
+func isIdentRune(r rune, first bool) bool {
+	if r == '_' || unicode.IsLetter(r) {
+		return true
+	}
+	return !first && unicode.IsDigit(r)
+}
+
+// Split the source into tokens
+func lex(src string) ([]token, error) {
+	toks := make([]token, 0)
+	rs := []rune(src)
+	i := 0
+	for i < len(rs) {
+		r := rs[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{kind: tLParen, text: "(", pos: pos})
+			i++
+		case r == ')':
+			toks = append(toks, token{kind: tRParen, text: ")", pos: pos})
+			i++
+		case r == '"' || r == '\'':
+			// Quoted string (the quote can be escaped with a backslash)
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(rs) && rs[j] != r; j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+				b.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, newError(pos, "unterminated string")
+			}
+			toks = append(toks, token{kind: tString, text: b.String(), pos: pos})
+			i = j + 1
+		case unicode.IsDigit(r) || r == '.':
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || rs[j] == 'e' || rs[j] == 'E' ||
+				((rs[j] == '-' || rs[j] == '+') && (rs[j-1] == 'e' || rs[j-1] == 'E'))) {
+				j++
+			}
+			text := string(rs[i:j])
+			num, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, newError(pos, "invalid number %s", text)
+			}
+			toks = append(toks, token{kind: tNumber, text: text, num: num, pos: pos})
+			i = j
+		case isIdentRune(r, true):
+			j := i
+			for j < len(rs) && isIdentRune(rs[j], false) {
+				j++
+			}
+			text := string(rs[i:j])
+			if op, ok := keywords[strings.ToLower(text)]; ok {
+				toks = append(toks, token{kind: tOp, text: op, pos: pos})
+			} else {
+				toks = append(toks, token{kind: tIdent, text: text, pos: pos})
+			}
+			i = j
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(rs[i:]), op) {
+					toks = append(toks, token{kind: tOp, text: op, pos: pos})
+					i += len([]rune(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, newError(pos, "unexpected character %q", r)
+			}
+		}
+	}
+	return append(toks, token{kind: tEOF, pos: len(rs) + 1}), nil
+}
@@ -0,0 +1,200 @@
+package expr
+
+import (
+	"regexp"
+)
+
+/*
+	Grammar:
+		or      := and { "||" and }
+		and     := not { "&&" not }
+		not     := "!" not | cmp
+		cmp     := primary [ op primary ]
+		primary := NUMBER | STRING | true | false | IDENT | "(" or ")"
+	with op in ==, !=, <, <=, >, >=, =~ and in.
+*/
+
+type parser struct {
+	toks []token
+	i    int
+	env  Env
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tEOF {
+		return nil, newError(t.pos, "unexpected %s", describe(t))
+	}
+	return n, nil
+}
+
+// Check that the operand of a logical operator is a boolean
+func checkBool(n node, t token) error {
+	if n.typ() != BOOL {
+		return newError(t.pos, "operator %s expects boolean operands, found a %s", t.text, n.typ())
+	}
+	return nil
+}
+
+func (p *parser) parseLogical(and bool, op string, sub func() (node, error)) (node, error) {
+	l, err := sub()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(op) {
+		t := p.next()
+		r, err := sub()
+		if err != nil {
+			return nil, err
+		}
+		if err = checkBool(l, t); err != nil {
+			return nil, err
+		}
+		if err = checkBool(r, t); err != nil {
+			return nil, err
+		}
+		l = &logical{and, l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical(false, "||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical(true, "&&", p.parseNot)
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isOp("!") {
+		t := p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err = checkBool(x, t); err != nil {
+			return nil, err
+		}
+		return &not{x}, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *parser) parseCmp() (node, error) {
+	lt := p.peek()
+	l, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("==", "!=", "<", "<=", ">", ">=", "=~", "in") {
+		return l, nil
+	}
+	t := p.next()
+	rt := p.peek()
+	r, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.text {
+	case "=~":
+		if l.typ() != STRING {
+			return nil, newError(lt.pos, "operator =~ expects a string on the left, found a %s", l.typ())
+		}
+		lit, ok := r.(*literal)
+		if !ok || lit.t != STRING {
+			return nil, newError(rt.pos, "operator =~ expects a quoted regular expression on the right")
+		}
+		re, err := regexp.Compile(lit.val.(string))
+		if err != nil {
+			return nil, newError(rt.pos, "invalid regular expression: %s", err)
+		}
+		return &match{l, re}, nil
+	case "in":
+		if l.typ() != STRING || r.typ() != LIST {
+			return nil, newError(t.pos, "operator in expects a string and a list, found a %s and a %s", l.typ(), r.typ())
+		}
+		return &in{l, r}, nil
+	case "<", "<=", ">", ">=":
+		if l.typ() != NUMBER || r.typ() != NUMBER {
+			return nil, newError(t.pos, "operator %s expects numbers, found a %s and a %s", t.text, l.typ(), r.typ())
+		}
+	default:
+		if l.typ() != r.typ() || l.typ() == LIST {
+			return nil, newError(t.pos, "cannot compare a %s and a %s with %s", l.typ(), r.typ(), t.text)
+		}
+	}
+	return &compare{t.text, l, r}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tNumber:
+		return &literal{NUMBER, t.num}, nil
+	case tString:
+		return &literal{STRING, t.text}, nil
+	case tIdent:
+		switch t.text {
+		case "true":
+			return &literal{BOOL, true}, nil
+		case "false":
+			return &literal{BOOL, false}, nil
+		}
+		vt, ok := p.env[t.text]
+		if !ok {
+			return nil, newError(t.pos, "unknown variable %s", t.text)
+		}
+		return &variable{vt, t.text}, nil
+	case tLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != tRParen {
+			return nil, newError(c.pos, "expected ) to close the ( at position %d, found %s", t.pos, describe(c))
+		}
+		return n, nil
+	}
+	return nil, newError(t.pos, "expected a value, found %s", describe(t))
+}
+
+// Describe a token in error messages
+func describe(t token) string {
+	switch t.kind {
+	case tEOF:
+		return "the end of the expression"
+	case tString:
+		return "string \"" + t.text + "\""
+	}
+	return "'" + t.text + "'"
+}
@@ -0,0 +1,86 @@
+package fannot
+
+import (
+	"github.com/hdevillers/go-fannot/align"
+	"github.com/hdevillers/go-needle"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+// Global alignment statistics (in percent)
+type Alignment struct {
+	Similarity float64
+	Identity   float64
+	QueryCov   float64 // Query residues aligned to a target residue
+	TargetCov  float64 // Target residues aligned to a query residue
+}
+
+// Global alignment tool
+type Aligner interface {
+	Align(q, t seq.Seq) (*Alignment, error)
+}
+
+// Default aligner based on EMBOSS needle
+type NeedleAligner struct {
+	Par *needle.Param
+}
+
+func NewNeedleAligner() *NeedleAligner {
+	return &NeedleAligner{needle.NewParam()}
+}
+
+func (na *NeedleAligner) Align(q, t seq.Seq) (*Alignment, error) {
+	ndl := needle.NewNeedle(q, t)
+	ndl.Par = na.Par
+	err := ndl.Align()
+	if err != nil {
+		return nil, err
+	}
+
+	aln := Alignment{
+		Similarity: ndl.Rst.GetSimilarityPct(),
+		Identity:   ndl.Rst.GetIdentityPct(),
+	}
+	aln.setCoverage(ndl.Rst.GetAlignedSeqA(), ndl.Rst.GetAlignedSeqB(), q.Length(), t.Length())
+
+	return &aln, nil
+}
+
+// Native aligner (no external dependency)
+type NativeAligner struct {
+	Par *align.Param
+}
+
+func NewNativeAligner() *NativeAligner {
+	return &NativeAligner{align.NewParam()}
+}
+
+func (na *NativeAligner) Align(q, t seq.Seq) (*Alignment, error) {
+	rst, err := align.Align(q.Sequence, t.Sequence, na.Par)
+	if err != nil {
+		return nil, err
+	}
+
+	aln := Alignment{
+		Similarity: rst.GetSimilarityPct(),
+		Identity:   rst.GetIdentityPct(),
+	}
+	aln.setCoverage(rst.GetAlignedSeqA(), rst.GetAlignedSeqB(), q.Length(), t.Length())
+
+	return &aln, nil
+}
+
+// Compute the query and target coverages from the aligned sequences
+func (aln *Alignment) setCoverage(sa, sb string, lq, lt int) {
+	if len(sa) != len(sb) || lq == 0 || lt == 0 {
+		return
+	}
+
+	nal := 0
+	for i := 0; i < len(sa); i++ {
+		if sa[i] != '-' && sb[i] != '-' {
+			nal++
+		}
+	}
+	aln.QueryCov = float64(nal) / float64(lq) * 100.0
+	aln.TargetCov = float64(nal) / float64(lt) * 100.0
+}
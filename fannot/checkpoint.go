@@ -0,0 +1,110 @@
+package fannot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// State of a functional annotation run, saved after each DB
+// round (and periodically within a round) to allow resuming
+type Checkpoint struct {
+	Queries  []string // Query IDs
+	DBs      []string // Reference DB IDs
+	DBi      int      // Index of the current DB round
+	Finished []bool
+	Checked  []bool // Queries processed in the current DB round
+	Results  []FAResult
+}
+
+// Save the current state of the run into a checkpoint file
+func (fa *Fannot) SaveCheckpoint(file string) error {
+	var cp Checkpoint
+
+	cp.Queries = make([]string, fa.NQueries)
+	for i := range fa.Queries {
+		cp.Queries[i] = fa.Queries[i].Id
+	}
+	cp.DBs = make([]string, len(fa.DBs))
+	for i := range fa.DBs {
+		cp.DBs[i] = fa.DBs[i].Id
+	}
+
+	// Write the checkpoint in a temporary file first so that a
+	// crash while saving does not corrupt the previous one
+	tmp := file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fw := bufio.NewWriter(f)
+	jw := json.NewEncoder(fw)
+
+	// Results cannot be modified while encoding
+	fa.lock.Lock()
+	cp.DBi = fa.DBi
+	cp.Finished = fa.Finished
+	cp.Checked = fa.Checked
+	cp.Results = fa.Results
+	err = jw.Encode(&cp)
+	fa.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = fw.Flush()
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+// Restore the state of a run from a checkpoint file, the next
+// call to NextDB() continues the saved DB round
+func (fa *Fannot) LoadCheckpoint(file string) error {
+	var cp Checkpoint
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(bufio.NewReader(f)).Decode(&cp)
+	if err != nil {
+		return fmt.Errorf("Failed to decode the checkpoint %s: %w", file, err)
+	}
+
+	// The run must be the same as the saved one
+	if len(cp.Queries) != fa.NQueries || len(cp.Finished) != fa.NQueries || len(cp.Checked) != fa.NQueries || len(cp.Results) != fa.NQueries {
+		return fmt.Errorf("The checkpoint contains %d queries, expected %d: %w", len(cp.Queries), fa.NQueries, ErrCheckpointMismatch)
+	}
+	for i := range cp.Queries {
+		if cp.Queries[i] != fa.Queries[i].Id {
+			return fmt.Errorf("Unexpected query %s in the checkpoint (expected %s): %w", cp.Queries[i], fa.Queries[i].Id, ErrCheckpointMismatch)
+		}
+	}
+	if len(cp.DBs) != len(fa.DBs) {
+		return fmt.Errorf("The checkpoint contains %d reference DBs, expected %d: %w", len(cp.DBs), len(fa.DBs), ErrCheckpointMismatch)
+	}
+	for i := range cp.DBs {
+		if cp.DBs[i] != fa.DBs[i].Id {
+			return fmt.Errorf("Unexpected reference DB %s in the checkpoint (expected %s): %w", cp.DBs[i], fa.DBs[i].Id, ErrCheckpointMismatch)
+		}
+	}
+
+	fa.Finished = cp.Finished
+	fa.Checked = cp.Checked
+	fa.Results = cp.Results
+	fa.DBi = cp.DBi - 1
+	fa.resumed = true
+
+	return nil
+}
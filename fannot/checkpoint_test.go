@@ -0,0 +1,80 @@
+package fannot
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hdevillers/go-seq/seq"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	queries := []seq.Seq{
+		newTestSeq("q1", "", "MKVLAGT"),
+		newTestSeq("q2", "", "MKVLAGT"),
+	}
+	fa := newTestFannot(queries, nil, nil, nil)
+	fa.Finished[0] = true
+	fa.Checked[0] = true
+	fa.Results[0].Product = "protein kinase A"
+	fa.Results[0].HitSim = 92.5
+
+	file := filepath.Join(t.TempDir(), "run.ckpt")
+	err := fa.SaveCheckpoint(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Resume in a fresh object
+	fb := newTestFannot(queries, nil, nil, nil)
+	err = fb.LoadCheckpoint(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fb.Finished[0] || fb.Finished[1] || !fb.Checked[0] {
+		t.Error("Finished and Checked states are not restored.")
+	}
+	if fb.Results[0].Product != "protein kinase A" || fb.Results[0].HitSim != 92.5 {
+		t.Errorf("Results are not restored, found %v.", fb.Results[0])
+	}
+
+	// The resumed round must be the saved one and keep checked queries
+	if fb.DBi != fa.DBi-1 {
+		t.Errorf("Expected DB index %d before NextDB, found %d.", fa.DBi-1, fb.DBi)
+	}
+
+	// Another query set must be rejected
+	fc := newTestFannot(queries[:1], nil, nil, nil)
+	err = fc.LoadCheckpoint(file)
+	if !errors.Is(err, ErrCheckpointMismatch) {
+		t.Errorf("Expected ErrCheckpointMismatch, found %v.", err)
+	}
+}
+
+// Queries are marked as checked together with their result so that a
+// checkpoint never saves the examined hits of an unchecked query
+func TestAnnotateSetsChecked(t *testing.T) {
+	queries := []seq.Seq{
+		newTestSeq("q1", "", "MKVLAGT"),
+		newTestSeq("q2", "", "MKVLAGT"),
+	}
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P1"}}}}
+	a := &fakeAligner{map[string]float64{"q1/P1": 90.0}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	for qi := range queries {
+		err := fa.annotate(qi)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fa.Checked[qi] {
+			t.Errorf("Query %s should be checked after its annotation.", queries[qi].Id)
+		}
+	}
+	if len(fa.Results[0].Hits) != 1 {
+		t.Errorf("Expected one examined hit for q1, found %d.", len(fa.Results[0].Hits))
+	}
+}
@@ -0,0 +1,152 @@
+package fannot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hdevillers/go-fannot/refdb"
+)
+
+// Qualifiers ignored when comparing products
+var productQualifiers = []string{"putative", "probable", "possible", "predicted"}
+
+// Consensus of the qualifying hits of a query (hits that satisfy one
+// of the rules)
+type consensus struct {
+	Hit       int      // Index of the selected hit (-1 if no hit qualifies)
+	Rule      int      // Index of the rule satisfied by the selected hit
+	TieBreak  string   // Criterion that broke a similarity tie with the selected hit
+	Support   int      // Number of qualifying hits with the majority product
+	Total     int      // Number of qualifying hits
+	Conflicts []string // Other products of the qualifying hits
+}
+
+// Group of qualifying hits sharing the same (normalized) product
+type productCluster struct {
+	Product string // Product of the first hit of the cluster
+	Hits    []int  // Indexes of the hits of the cluster
+	Best    int    // Index of the most similar hit of the cluster
+}
+
+// Normalize a product description before comparison: lower case, no
+// punctuation and no uncertainty qualifier
+func normalizeProduct(p string) string {
+	p = strings.ToLower(p)
+	p = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(p, " ")
+	words := strings.Fields(p)
+	for len(words) > 1 && isProductQualifier(words[0]) {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func isProductQualifier(w string) bool {
+	for _, q := range productQualifiers {
+		if w == q {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the index of the first rule satisfied by a hit (-1 if none)
+func firstRule(rules []Rule, h *HitAttrs) (int, error) {
+	for ri, rule := range rules {
+		ok, err := rule.Accept(h)
+		if err != nil {
+			return ri, err
+		}
+		if ok {
+			return ri, nil
+		}
+	}
+	return -1, nil
+}
+
+// Cluster the products of the qualifying hits and select the most
+// similar hit of the majority product (ties between products are broken
+// by the best hit of each cluster, see the tie-break chain)
+func newConsensus(hits []HitInfo, attrs []HitAttrs, metas []*refdb.Meta, rules []Rule, chain []string) (*consensus, error) {
+	cns := consensus{Hit: -1, Rule: -1}
+	clusters := make([]productCluster, 0)
+	index := make(map[string]int)
+	ranks := make([]int, len(hits))
+
+	for i := range hits {
+		ri, err := firstRule(rules, &attrs[i])
+		if err != nil {
+			return nil, fmt.Errorf("Failed to evaluate the rule %d: %w", ri+1, err)
+		}
+		ranks[i] = ri
+		if ri < 0 {
+			continue
+		}
+		cns.Total++
+
+		product := metas[i].Desc
+		key := normalizeProduct(product)
+		ci, ok := index[key]
+		if !ok {
+			ci = len(clusters)
+			index[key] = ci
+			clusters = append(clusters, productCluster{product, []int{i}, i})
+			continue
+		}
+		clusters[ci].Hits = append(clusters[ci].Hits, i)
+		if ok, _ := preferHit(chain, hits, attrs, i, clusters[ci].Best); ok {
+			clusters[ci].Best = i
+		}
+	}
+	if cns.Total == 0 {
+		return &cns, nil
+	}
+
+	// Majority product
+	major := 0
+	for ci := 1; ci < len(clusters); ci++ {
+		c, m := clusters[ci], clusters[major]
+		if len(c.Hits) != len(m.Hits) {
+			if len(c.Hits) > len(m.Hits) {
+				major = ci
+			}
+			continue
+		}
+		if ok, _ := preferHit(chain, hits, attrs, c.Best, m.Best); ok {
+			major = ci
+		}
+	}
+	cns.Hit = clusters[major].Best
+	cns.Rule = ranks[cns.Hit]
+	cns.Support = len(clusters[major].Hits)
+
+	// Criterion that decided the majority product (between clusters of
+	// the same size) or the best hit of the cluster
+	rivals := make([]int, 0)
+	for _, c := range clusters {
+		if len(c.Hits) == cns.Support {
+			rivals = append(rivals, c.Best)
+		}
+	}
+	cns.TieBreak = decidingTie(chain, hits, attrs, cns.Hit, rivals)
+	if cns.TieBreak == "" {
+		cns.TieBreak = decidingTie(chain, hits, attrs, cns.Hit, clusters[major].Hits)
+	}
+	for ci, c := range clusters {
+		if ci != major {
+			cns.Conflicts = append(cns.Conflicts, fmt.Sprintf("%s (%d)", c.Product, len(c.Hits)))
+		}
+	}
+
+	return &cns, nil
+}
+
+// Indicate if the qualifying hits disagree
+func (c *consensus) HasConflict() bool {
+	return len(c.Conflicts) > 0
+}
+
+// Short description of the consensus support
+func (c *consensus) String() string {
+	return fmt.Sprintf("%d/%d", c.Support, c.Total)
+}
@@ -0,0 +1,144 @@
+package fannot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+func TestConsensusNormalizeProduct(t *testing.T) {
+	for _, p := range []string{"Protein kinase A", "Putative protein kinase A.", "probable protein-kinase a"} {
+		if n := normalizeProduct(p); n != "protein kinase a" {
+			t.Errorf("Unexpected normalized product of %s: %s.", p, n)
+		}
+	}
+	if n := normalizeProduct("Putative"); n != "putative" {
+		t.Errorf("A single qualifier should be kept, found %s.", n)
+	}
+}
+
+func TestNewConsensus(t *testing.T) {
+	rules := NewParam().Rules
+	hits := []HitInfo{
+		{Id: "P1", HitStats: HitStats{Similarity: 95.0, LengthRatio: 1.0}},
+		{Id: "P2", HitStats: HitStats{Similarity: 85.0, LengthRatio: 1.0}},
+		{Id: "P3", HitStats: HitStats{Similarity: 60.0, LengthRatio: 1.0}},
+		{Id: "P4", HitStats: HitStats{Similarity: 85.0, LengthRatio: 1.0}},
+		{Id: "P5", HitStats: HitStats{Similarity: 20.0, LengthRatio: 1.0}},
+	}
+	attrs := make([]HitAttrs, len(hits))
+	for i := range hits {
+		attrs[i].HitStats = hits[i].HitStats
+	}
+	metas := []*refdb.Meta{
+		{Desc: "Glucokinase"},
+		{Desc: "Protein kinase A"},
+		{Desc: "putative protein kinase A."},
+		{Desc: "Protein kinase A"},
+		{Desc: "Glucokinase"},
+	}
+	attrs[3].Reviewed = true
+
+	// P5 does not qualify, P4 is preferred to P2 (reviewed) and P3
+	// satisfies the second rule only
+	cns, err := newConsensus(hits, attrs, metas, rules, NewParam().Tie_brk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cns.Hit != 3 || cns.Rule != 0 || cns.String() != "3/4" || cns.TieBreak != TIE_REVIEWED {
+		t.Errorf("Expected P4 (rule 1, 3/4, reviewed), found hit %d (rule %d, %s, %s).", cns.Hit, cns.Rule, cns, cns.TieBreak)
+	}
+	if !cns.HasConflict() || cns.Conflicts[0] != "Glucokinase (1)" {
+		t.Errorf("Unexpected conflicts: %v.", cns.Conflicts)
+	}
+
+	// No qualifying hit
+	cns, err = newConsensus(hits[4:], attrs[4:], metas[4:], rules, NewParam().Tie_brk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cns.Hit != -1 || cns.HasConflict() {
+		t.Errorf("Expected no consensus, found hit %d (%v).", cns.Hit, cns.Conflicts)
+	}
+}
+
+func TestFindFunctionConsensus(t *testing.T) {
+	queries := []seq.Seq{newTestSeq("q1", "", "MKVLAGT")}
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+		newTestSeq("P2", "Putative protein kinase A.::PKA2::::Candida albicans::", "MKVLAGT"),
+		newTestSeq("P3", "Glucokinase::GLK1::::Homo sapiens::", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P3"}, {Id: "P1"}, {Id: "P2"}}}}
+	a := &fakeAligner{map[string]float64{
+		"q1/P1": 85.0,
+		"q1/P2": 82.0,
+		"q1/P3": 95.0,
+	}}
+
+	p, err := NewParamFromJson("../examples/consensus_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cfl := range []string{CNS_NOTE, CNS_LOWER} {
+		fa := newTestFannot(queries, entries, s, a)
+		p.Cns_cfl = cfl
+		fa.SetParam(p)
+		err = runTestFannot(fa)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The majority product is supported by P1 and P2 (P1 is more similar)
+		r := fa.Results[0]
+		if r.GeneID != "P1" || r.Consensus != "2/3" {
+			t.Errorf("Expected the annotation of P1 (2/3), found %s (%s).", r.GeneID, r.Consensus)
+		}
+		if !strings.HasPrefix(r.Rule, "rule 1:") {
+			t.Errorf("Expected the first rule to be recorded, found %s.", r.Rule)
+		}
+		noted := strings.Contains(r.Note, ", conflicting annotations: Glucokinase (1)")
+		lowered := strings.Contains(r.Note, ", status lowered to rule 2 by conflicting annotations: Glucokinase (1)")
+		if cfl == CNS_NOTE && (r.Status != 2 || !noted || lowered) {
+			t.Errorf("Expected status 2 and a conflict note, found %d (%s).", r.Status, r.Note)
+		}
+		if cfl == CNS_LOWER && (r.Status != 1 || noted || !lowered) {
+			t.Errorf("Expected the status to be lowered to 1, found %d (%s).", r.Status, r.Note)
+		}
+	}
+}
+
+// The first annotation and the overwrite build the same result
+func TestConsensusOverwrite(t *testing.T) {
+	queries := []seq.Seq{newTestSeq("q1", "", "MKVLAGT")}
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+		newTestSeq("P2", "Glucokinase::GLK1::::Homo sapiens::", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P1"}, {Id: "P2"}}}}
+	a := &fakeAligner{map[string]float64{"q1/P1": 95.0, "q1/P2": 85.0}}
+
+	p, err := NewParamFromJson("../examples/consensus_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fa := newTestFannot(queries, entries, s, a)
+	fa.SetParam(p)
+	fa.DBs[0].OverWrite = true
+	fa.Finished[0] = true
+	fa.Results[0] = FAResult{Product: "kinase", Note: "similar to kinase", Status: 1, HitSim: 60.0, HitLR: 0.7}
+	err = runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := fa.Results[0]
+	if !r.HitOW || r.GeneID != "P1" || r.Consensus != "1/2" {
+		t.Fatalf("Expected the overwrite of q1 by P1 (1/2), found %t, %s (%s).", r.HitOW, r.GeneID, r.Consensus)
+	}
+	if n := strings.Count(r.Note, "conflicting annotations"); n != 1 {
+		t.Errorf("Expected a single conflict note, found %d (%s).", n, r.Note)
+	}
+}
@@ -0,0 +1,147 @@
+package fannot
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+// Default DIAMOND settings
+const (
+	D_DMND_EVALUE  float64 = 0.001
+	D_DMND_THREADS int     = 2
+	D_DMND_TARGETS int     = 25
+)
+
+// Searcher based on DIAMOND BLASTP (batch mode)
+type DiamondSearcher struct {
+	Evalue      float64
+	Threads     int
+	MaxTargets  int
+	Sensitivity string // Sensitivity mode (e.g. more-sensitive), empty for default
+}
+
+func NewDiamondSearcher() *DiamondSearcher {
+	return &DiamondSearcher{
+		Evalue:     D_DMND_EVALUE,
+		Threads:    D_DMND_THREADS,
+		MaxTargets: D_DMND_TARGETS,
+	}
+}
+
+func (ds *DiamondSearcher) Search(q seq.Seq, db *refdb.Refdb) ([]Candidate, error) {
+	res, err := ds.SearchBatch([]seq.Seq{q}, db)
+	if err != nil {
+		return nil, err
+	}
+	return res[q.Id], nil
+}
+
+func (ds *DiamondSearcher) SearchBatch(qs []seq.Seq, db *refdb.Refdb) (map[string][]Candidate, error) {
+	if !db.HasBackend(refdb.DIAMOND_BACKEND) {
+		return nil, fmt.Errorf("No DIAMOND DB available for the reference DB %s.", db.Id)
+	}
+
+	// Write the queries in a temporary FASTA file
+	qf, err := writeTempFasta(qs)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(qf)
+
+	// Prepare the command line (tabular output)
+	cmd := exec.Command("diamond", "blastp",
+		"--db", db.Diamonddb,
+		"--query", qf,
+		"--outfmt", "6", "qseqid", "sseqid", "evalue", "bitscore",
+		"--evalue", fmt.Sprintf("%g", ds.Evalue),
+		"--threads", strconv.Itoa(ds.Threads),
+		"--max-target-seqs", strconv.Itoa(ds.MaxTargets),
+		"--quiet",
+	)
+	if ds.Sensitivity != "" {
+		cmd.Args = append(cmd.Args, "--"+ds.Sensitivity)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTabularHits(bytes.NewReader(out))
+}
+
+// Write sequences into a temporary FASTA file and return its path
+func writeTempFasta(qs []seq.Seq) (string, error) {
+	f, err := ioutil.TempFile("", "fannot-*.fasta")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fw := bufio.NewWriter(f)
+	for _, q := range qs {
+		fmt.Fprintf(fw, ">%s\n%s\n", q.Id, q.Sequence)
+	}
+	err = fw.Flush()
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// Parse a tabular output (qseqid, sseqid, evalue, bitscore) and
+// group hits by query. Hits are kept in the output order (i.e.,
+// ranked) and only the first HSP of each hit is considered. The
+// subject field can be a title (only the first word is kept).
+func parseTabularHits(r io.Reader) (map[string][]Candidate, error) {
+	res := make(map[string][]Candidate)
+	seen := make(map[string]bool)
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		elem := strings.Split(line, "\t")
+		if len(elem) < 4 {
+			return nil, errors.New("Malformed tabular search output: " + line)
+		}
+
+		var c Candidate
+		var err error
+		c.Id = strings.SplitN(elem[1], " ", 2)[0]
+
+		// Skip additional HSPs of the same hit
+		key := elem[0] + "\t" + c.Id
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		c.Evalue, err = strconv.ParseFloat(elem[2], 64)
+		if err != nil {
+			return nil, err
+		}
+		c.BitScore, err = strconv.ParseFloat(elem[3], 64)
+		if err != nil {
+			return nil, err
+		}
+		res[elem[0]] = append(res[elem[0]], c)
+	}
+
+	return res, sc.Err()
+}
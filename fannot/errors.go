@@ -0,0 +1,14 @@
+package fannot
+
+import (
+	"errors"
+
+	"github.com/hdevillers/go-fannot/refdb"
+)
+
+// Errors returned by the functional annotation process
+var (
+	ErrHitMissingFromFasta = errors.New("hit missing from the reference FASTA")
+	ErrMalformedHitDesc    = refdb.ErrMalformedDesc
+	ErrCheckpointMismatch  = errors.New("checkpoint does not match the run")
+)
@@ -1,18 +1,23 @@
 package fannot
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"unicode"
 
-	"github.com/hdevillers/go-blast"
 	"github.com/hdevillers/go-fannot/ips"
 	"github.com/hdevillers/go-fannot/refdb"
-	"github.com/hdevillers/go-needle"
+	"github.com/hdevillers/go-fannot/swiss"
 	"github.com/hdevillers/go-seq/seq"
-	"github.com/hdevillers/go-seq/utils"
+	"github.com/hdevillers/go-seq/seqio/fasta"
+	gzip "github.com/klauspost/pgzip"
 )
 
 // Initialize default variable
@@ -21,35 +26,83 @@ const (
 	UNREVIEWED_DB string = "uniprot"
 )
 
+// Available homology search tools
+const (
+	BLAST_SEARCH   string = "blast"
+	DIAMOND_SEARCH string = "diamond"
+	MMSEQS_SEARCH  string = "mmseqs"
+)
+
+// Available global aligners
+const (
+	NEEDLE_ALIGNER string = "needle"
+	NATIVE_ALIGNER string = "native"
+)
+
+// Available output formats of the results
+const (
+	TSV_FORMAT   string = "tsv"
+	JSON_FORMAT  string = "json"
+	JSONL_FORMAT string = "jsonl"
+)
+
+// Maximal length of a FASTA line (genome sequences may not be wrapped)
+const (
+	MAX_LINE_SIZE  int    = 1 << 30
+	HIT_UNPARSABLE string = "unparsable description" // Legacy description of the hit cannot be parsed
+)
+
 // DEFINING STRUCTURES
 
+// Hit examined while searching the annotation of a query
+type HitInfo struct {
+	DB      string `json:"db"`
+	Id      string `json:"id"`
+	Rank    int    `json:"rank"`              // Zero for skipped hits
+	Skipped string `json:"skipped,omitempty"` // Reason why the hit was not examined
+	HitStats
+}
+
 // Functional Annotation Results
 type FAResult struct {
-	Product  string
-	Note     string
-	Locus    string
-	Name     string
-	Status   int
-	Organism string
-	GeneID   string
-	CopyGID  bool
-	RefID    string
-	HitSim   float64
-	HitLR    float64
-	HitNum   int
-	HitOW    bool
-	IpsId    []string
-	IpsAnnot []string
-	Reviewed bool
+	Product   string
+	Note      string
+	Locus     string
+	Name      string
+	Status    int
+	Organism  string
+	GeneID    string
+	CopyGID   bool
+	RefID     string
+	HitSim    float64
+	HitLR     float64
+	HitNum    int
+	HitOW     bool
+	IpsId     []string
+	IpsAnnot  []string
+	Reviewed  bool
+	DescType  string         // Type of the reference name (RecName or SubName)
+	Rule      string         // Description of the rule that validated the hit
+	RuleSet   string         // Name of the rule set of the rule
+	Consensus string         // Support of the product among the qualifying hits (consensus mode)
+	TieBreak  string         // Criterion that broke a similarity tie with the selected hit
+	Rbh       string         // Outcome of the reciprocal best hit check (gene name copy)
+	RbhHit    string         // Best hit of the reference among the queries
+	ParalogOf string         // Query that keeps the duplicated gene name
+	GoTerms   []refdb.GoTerm // GO terms of the reference (see Cpy_ont)
+	EC        []string       // EC numbers of the reference
+	Pathways  []string       // Pathways of the reference
+	Hits      []HitInfo      // All hits examined (in every reference DB)
 }
 
-func NewFAResult() *FAResult {
+// Create the result of a query without annotation (see Param)
+func NewFAResult(p *Param, gid string) *FAResult {
 	return &FAResult{
-		UNKNOWN_FUNC,
-		UNKNOWN_FUNC,
+		p.Unk_ann,
+		p.UnknownNote(gid),
 		"Null",
 		"Null",
-		0,
+		p.Unk_sta,
 		"Null",
 		"Null",
 		false,
@@ -61,16 +114,43 @@ func NewFAResult() *FAResult {
 		make([]string, 0),
 		make([]string, 0),
 		false,
+		"",
+		"",
+		"",
+		"",
+		"",
+		"",
+		"",
+		"",
+		make([]refdb.GoTerm, 0),
+		make([]string, 0),
+		make([]string, 0),
+		make([]HitInfo, 0),
+	}
+}
+
+// Indicate if the annotation comes from a reference hit
+func (far *FAResult) HasHit() bool {
+	return far.GeneID != "" && far.GeneID != "Null"
+}
+
+// Build the annotation of a query from the description of a hit written
+// by older versions (see refdb.ParseDesc)
+func ParseHitDesc(hd string, hid string, rid string, hs int, pre string, eq bool, re bool, gn bool) (*FAResult, error) {
+	m, err := refdb.ParseDesc(hid, hd)
+	if err != nil {
+		return nil, err
 	}
+	return ParseHitMeta(m, rid, hs, pre, eq, re, gn), nil
 }
 
-func ParseHitDesc(hd string, hid string, rid string, hs int, pre string, eq bool, re bool, gn bool) *FAResult {
+// Build the annotation of a query from the metadata of a hit
+func ParseHitMeta(m *refdb.Meta, rid string, hs int, pre string, eq bool, re bool, gn bool) *FAResult {
 	var far FAResult
-	values := strings.Split(hd, "::")
 
-	far.Product = values[0]
+	far.Product = m.Desc
 	far.Status = hs
-	far.GeneID = hid
+	far.GeneID = m.Access
 	far.RefID = rid
 	far.CopyGID = gn
 	far.Reviewed = re
@@ -83,9 +163,7 @@ func ParseHitDesc(hd string, hid string, rid string, hs int, pre string, eq bool
 	}
 
 	// Keep only species name (delete strain data)
-	tmpOrg := strings.Split(values[3], " (")
-	tmpOrg[0] = regexp.MustCompile(`\.$`).ReplaceAllString(tmpOrg[0], "")
-	far.Organism = tmpOrg[0]
+	far.Organism = cleanOrganism(m.Organism)
 
 	// Db type
 	dbType := UNREVIEWED_DB
@@ -99,15 +177,15 @@ func ParseHitDesc(hd string, hid string, rid string, hs int, pre string, eq bool
 		far.Note = fmt.Sprintf("%s %s|%s %s", pre, dbType, far.GeneID, far.Organism)
 	}
 
-	if values[2] != "" {
-		far.Note += " " + values[2]
-		far.Locus = values[2]
+	if m.Locus != "" {
+		far.Note += " " + m.Locus
+		far.Locus = m.Locus
 		// Clean up product: remove locus tag references
 		far.Product = regexp.MustCompile(" "+far.Locus).ReplaceAllString(far.Product, "")
 	}
-	if values[1] != "" {
-		far.Note += " " + values[1]
-		far.Name = values[1]
+	if m.Name != "" {
+		far.Note += " " + m.Name
+		far.Name = m.Name
 		reName := regexp.MustCompile(" " + far.Name)
 		if reName.MatchString(far.Product) {
 			protName := strings.Title(strings.ToLower(far.Name)) + "p"
@@ -122,15 +200,23 @@ func ParseHitDesc(hd string, hid string, rid string, hs int, pre string, eq bool
 		far.Note += ", unreviewed"
 	}
 
+	// Products from submitted names (TrEMBL) are not curated
+	far.DescType = m.DescType
+	subName := far.DescType == swiss.DESC_SUBNAME
+	if subName {
+		far.Note += ", submitted name"
+	}
+
 	// Add putative to the product if the gene name should not be transfered
-	if !gn {
+	// or if it comes from a submitted name
+	if !gn || subName {
 		if !regexp.MustCompile(`^putative`).MatchString(far.Product) {
 			far.Product = "putative " + far.Product
 		}
 	}
 
-	if values[4] != "" {
-		far.Note += ", " + values[4]
+	if m.Function != "" {
+		far.Note += ", " + m.Function
 	} else {
 		far.Note += ", " + far.Product
 	}
@@ -139,23 +225,50 @@ func ParseHitDesc(hd string, hid string, rid string, hs int, pre string, eq bool
 }
 
 func (far *FAResult) PrintFAResult(gid string) {
+	far.WriteFAResult(os.Stdout, gid)
+}
+
+// Write the result as a TSV line
+func (far *FAResult) WriteFAResult(w io.Writer, gid string) error {
 	cg := 0
 	if far.CopyGID {
 		cg = 1
 	}
 
-	fmt.Printf(
-		"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%d\t%.03f\t%.03f\t%s\t%d\t%t\n",
+	_, err := fmt.Fprintf(w,
+		"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%d\t%.03f\t%.03f\t%s\t%d\t%t\t%s\t%s\t%s\t%s\n",
 		gid, far.Product, far.Note, far.Organism,
 		far.GeneID, far.Locus, far.Name, cg,
 		strings.Join(far.IpsId, ","), strings.Join(far.IpsAnnot, "; "), far.Status,
 		far.HitSim, far.HitLR, far.RefID, far.HitNum,
-		far.HitOW,
+		far.HitOW, far.RuleSet,
+		strings.Join(far.GoIds(), ","), strings.Join(far.EC, ","), strings.Join(far.Pathways, "|"),
 	)
+	return err
 }
 
 // UTILS
 
+// Keep only the species name of an organism (delete strain data)
+func cleanOrganism(org string) string {
+	tmpOrg := strings.Split(org, " (")
+	return regexp.MustCompile(`\.$`).ReplaceAllString(tmpOrg[0], "")
+}
+
+// Build the rule attributes of a hit from its statistics and its metadata
+func newHitAttrs(hs HitStats, m *refdb.Meta, db *refdb.Refdb) HitAttrs {
+	return HitAttrs{
+		HitStats: hs,
+		Db:       db.Id,
+		Reviewed: m.Reviewed,
+		Name:     m.Name,
+		Organism: cleanOrganism(m.Organism),
+		Function: m.Function,
+		Lineage:  m.Lineage,
+		Evidence: m.Evidence,
+	}
+}
+
 // Return the minimal length ratio
 func getMinLengthRatio(l1, l2 int) float64 {
 	if l1 < l2 {
@@ -165,54 +278,122 @@ func getMinLengthRatio(l1, l2 int) float64 {
 	}
 }
 
+// Read a (possibly gzipped) FASTA file and call fn on each sequence
+func readFasta(file string, fn func(s seq.Seq)) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var fs *bufio.Scanner
+	if regexp.MustCompile(`\.gz$`).MatchString(file) {
+		fgz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer fgz.Close()
+		fs = bufio.NewScanner(fgz)
+	} else {
+		fs = bufio.NewScanner(f)
+	}
+	fs.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), MAX_LINE_SIZE)
+
+	fr := fasta.NewReader(fs)
+	for !fr.IsEOF() {
+		s, err := fr.Read()
+		// NOTE: scanning errors are hidden by the reader
+		if fs.Err() != nil {
+			return fmt.Errorf("Failed to read %s: %w", file, fs.Err())
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read %s: %w", file, err)
+		}
+		// NOTE: the reader returns an empty sequence at the end
+		if s.Length() == 0 {
+			break
+		}
+		fn(s)
+	}
+
+	return nil
+}
+
 // Print functional annotation table header
 func PrintFAResultsHeader() {
-	fmt.Println("GeneID\tProduct\tNote\tOrganism\tRefID\tRefLocus\tRefName\tCopyName\tIPSID\tIPSAnnot\tStatus\tSimilarity\tLengthRatio\tDBID\tHitNum\tOverWritten")
+	WriteFAResultsHeader(os.Stdout)
+}
+
+func WriteFAResultsHeader(w io.Writer) error {
+	_, err := fmt.Fprintln(w, "GeneID\tProduct\tNote\tOrganism\tRefID\tRefLocus\tRefName\tCopyName\tIPSID\tIPSAnnot\tStatus\tSimilarity\tLengthRatio\tDBID\tHitNum\tOverWritten\tRuleSet\tGO\tEC\tPathway")
+	return err
 }
 
 // Functional annotation main structure
 type Fannot struct {
-	Queries   []seq.Seq
-	NQueries  int
-	DBs       []refdb.Refdb
-	DBi       int
-	DBEntries map[string]seq.Seq
-	Finished  []bool
-	Results   []FAResult
-	FaPar     Param
-	BlastPar  blast.Param
-	NeedlePar needle.Param
-	Ips       ips.Ips
+	Queries    []seq.Seq
+	NQueries   int
+	DBs        []refdb.Refdb
+	DBi        int
+	DBEntries  map[string]seq.Seq
+	DBSeqs     *refdb.SeqStore     // Indexed sequences of the current DB (nil for older DBs)
+	DBMeta     *refdb.MetaStore    // Metadata of the current DB entries (nil for older DBs)
+	QueryDB    *refdb.Refdb        // Search DB of the queries (reciprocal best hits)
+	Candidates map[int][]Candidate // Batch search results of the current DB
+	Finished   []bool
+	Checked    []bool // Queries processed in the current DB round
+	Results    []FAResult
+	FaPar      Param
+	Searcher   Searcher
+	Aligner    Aligner
+	Ips        ips.Ips
+	err        error
+	lock       sync.Mutex // Protect results while saving a checkpoint
+	resumed    bool       // The current DB round is resumed from a checkpoint
 }
 
-func NewFannot(i string) *Fannot {
+func NewFannot(i string) (*Fannot, error) {
 	var fa Fannot
 
 	// Load the query sequences
-	fa.NQueries = utils.LoadSeqInArray(i, "fasta", &fa.Queries)
+	err := readFasta(i, func(s seq.Seq) {
+		fa.Queries = append(fa.Queries, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	fa.NQueries = len(fa.Queries)
 
-	// Init. BLAST and NEEDLE parameter setings
-	fa.BlastPar = *blast.NewParam()
-	fa.NeedlePar = *needle.NewParam()
+	// Init. default search and alignment tools (BLAST and NEEDLE)
+	fa.Searcher = NewBlastSearcher()
+	fa.Aligner = NewNeedleAligner()
 
 	// Init. the IPS object
 	fa.Ips = *ips.NewIps()
 
 	// Init. results and Finished variables
 	fa.Finished = make([]bool, fa.NQueries)
+	fa.Checked = make([]bool, fa.NQueries)
 	fa.Results = make([]FAResult, fa.NQueries)
-	for i := 0; i < fa.NQueries; i++ {
-		// Set default result (when no match is found)
-		fa.Results[i] = *NewFAResult()
-	}
 
-	// Setup default threshold
-	fa.FaPar = *NewParam()
+	// Setup default threshold and results
+	fa.SetParam(NewParam())
 
-	return &fa
+	return &fa, nil
 }
 
-func (fa *Fannot) GetDBs(i, d string) {
+// Set the parameters and reset the results of the queries without
+// annotation (when no match is found)
+func (fa *Fannot) SetParam(p *Param) {
+	fa.FaPar = *p
+	for i := 0; i < fa.NQueries; i++ {
+		if !fa.Finished[i] {
+			fa.Results[i] = *NewFAResult(p, fa.Queries[i].Id)
+		}
+	}
+}
+
+func (fa *Fannot) GetDBs(i, d string) error {
 	// split ids
 	ids := strings.Split(i, ",")
 
@@ -222,149 +403,426 @@ func (fa *Fannot) GetDBs(i, d string) {
 
 	// Fill with found DB
 	for _, id := range ids {
-		newDB := refdb.FindRefDB(id, d)
+		newDB, err := refdb.FindRefDB(id, d)
+		if err != nil {
+			return err
+		}
 		fa.DBs = append(fa.DBs, *newDB)
 	}
+
+	return nil
 }
 
-func (fa *Fannot) LoadDBEntries() {
+func (fa *Fannot) LoadDBEntries() error {
+	// Open the metadata store (DBs built by older versions keep the
+	// metadata in the FASTA descriptions)
+	if fa.DBs[fa.DBi].Metadata != "" {
+		ms, err := fa.DBs[fa.DBi].OpenMeta()
+		if err != nil {
+			return fmt.Errorf("Failed to open the metadata of the reference DB %s: %w", fa.DBs[fa.DBi].Id, err)
+		}
+		fa.DBMeta = ms
+	}
+
+	// Read the hit sequences from the indexed FASTA file, DBs built by
+	// older versions are loaded in memory
+	if fa.DBs[fa.DBi].FastaIndex != "" {
+		ss, err := fa.DBs[fa.DBi].OpenSeqs()
+		if err != nil {
+			return fmt.Errorf("Failed to open the sequences of the reference DB %s: %w", fa.DBs[fa.DBi].Id, err)
+		}
+		fa.DBSeqs = ss
+		return nil
+	}
+
 	// Load DB entries (FASTA)
-	utils.LoadSeqInMap(fa.DBs[fa.DBi].Fasta, "fasta", &fa.DBEntries)
+	return readFasta(fa.DBs[fa.DBi].Fasta, func(s seq.Seq) {
+		fa.DBEntries[s.Id] = s
+	})
+}
+
+// Return the sequence of an entry of the current DB
+func (fa *Fannot) dbEntry(id string) (seq.Seq, error) {
+	if fa.DBSeqs != nil {
+		s, err := fa.DBSeqs.Get(id)
+		if errors.Is(err, refdb.ErrSeqNotFound) {
+			err = fmt.Errorf("Failed to find the hit %s in the reference DB %s: %w", id, fa.DBs[fa.DBi].Id, ErrHitMissingFromFasta)
+		}
+		return s, err
+	}
+	s, ok := fa.DBEntries[id]
+	if !ok {
+		return s, fmt.Errorf("Failed to find the hit %s in the reference DB %s: %w", id, fa.DBs[fa.DBi].Id, ErrHitMissingFromFasta)
+	}
+	return s, nil
+}
+
+// Return the metadata of a hit of the current DB
+func (fa *Fannot) hitMeta(hit seq.Seq) (*refdb.Meta, error) {
+	if fa.DBMeta != nil {
+		return fa.DBMeta.Get(hit.Id)
+	}
+	m, err := refdb.ParseDesc(hit.Id, hit.Desc)
+	if err != nil {
+		return nil, err
+	}
+	// The status of the entries is not in older descriptions
+	m.Reviewed = fa.DBs[fa.DBi].Reviewed
+	return m, nil
 }
 
+// Move to the next reference DB, return false when all DBs have
+// been processed or if the DB cannot be loaded (see Err())
 func (fa *Fannot) NextDB() bool {
 	fa.DBEntries = make(map[string]seq.Seq)
+	fa.Candidates = make(map[int][]Candidate)
+	if fa.DBSeqs != nil {
+		fa.DBSeqs.Close()
+		fa.DBSeqs = nil
+	}
+	if fa.DBMeta != nil {
+		fa.DBMeta.Close()
+		fa.DBMeta = nil
+	}
+	if fa.resumed {
+		// Keep the queries already processed in the resumed round
+		fa.resumed = false
+	} else {
+		fa.Checked = make([]bool, fa.NQueries)
+	}
 	fa.DBi++
 	if fa.DBi < len(fa.DBs) {
-		fa.LoadDBEntries()
-		return true
+		fa.err = fa.LoadDBEntries()
+		return fa.err == nil
 	} else {
 		return false
 	}
 }
 
-// Go-routine that treat one given gene
-func (fa *Fannot) FindFunction(queryChan chan int, threadChan chan int) {
-	// Init. search tools
-	blt := blast.NewBlast()
-	blt.Par = &fa.BlastPar
-	blt.Db = fa.DBs[fa.DBi].Blastdb
+// Return the error that stopped the DB iteration (if any)
+func (fa *Fannot) Err() error {
+	return fa.err
+}
+
+// Search the given queries at once in the current DB if the
+// searcher supports batch mode (otherwise, queries will be
+// searched one by one in FindFunction)
+func (fa *Fannot) SearchBatch(qis []int) error {
+	bs, ok := fa.Searcher.(BatchSearcher)
+	if !ok || len(qis) == 0 {
+		return nil
+	}
+
+	qs := make([]seq.Seq, len(qis))
+	for i, qi := range qis {
+		qs[i] = fa.Queries[qi]
+	}
+
+	res, err := bs.SearchBatch(qs, &fa.DBs[fa.DBi])
+	if err != nil {
+		return err
+	}
+
+	// Queries without hit are stored too (as searched)
+	for _, qi := range qis {
+		fa.Candidates[qi] = res[fa.Queries[qi].Id]
+	}
+
+	return nil
+}
+
+// Return the candidate hits of a query in the current DB
+func (fa *Fannot) search(qi int) ([]Candidate, error) {
+	if hits, ok := fa.Candidates[qi]; ok {
+		return hits, nil
+	}
+	return fa.Searcher.Search(fa.Queries[qi], &fa.DBs[fa.DBi])
+}
+
+// Go-routine that treat one given gene, the first error met is
+// thrown in threadChan when terminating (nil if none)
+func (fa *Fannot) FindFunction(queryChan chan int, threadChan chan error) {
+	var err error
 
 	// Get the query id(s) from the chan
 	for qi := range queryChan {
-		/* First step: BLAST */
-
-		// Add the query and run blast
-		blt.AddQuery(fa.Queries[qi])
-		err := blt.Search()
-		if err != nil {
-			panic(err)
+		// After a failure, only drain the remaining queries
+		if err == nil {
+			err = fa.annotate(qi)
 		}
+	}
 
-		// Parse blast result
-		results := blt.Rst.Iterations[0]
-		if len(results.Hits) > 0 {
-			chkhit := 0 // Number if hit checked
-			bestHitId := "NULL"
-			bestHitDesc := ""
-			bestHitSim := 0.0
-			bestHitLen := 0
-			bestHitStatus := 0
-			bestHitNum := 0
-			bestHitCanOwr := false
-			bestHitCpyGn := true
-			bestHitPre := ""
-
-		HITS:
-			for _, hit := range results.Hits {
-				// For each hit, compute the global alignment and extract the similarity
-				hitId := hit.GetHitId()
-				hitSeq, test := fa.DBEntries[hitId]
-				if !test {
-					panic(fmt.Sprintf("Failed to find the hit %s in the reference DB (%s).", hitId, fa.DBs[fa.DBi].Id))
-				}
-				ndl := needle.NewNeedle(fa.Queries[qi], hitSeq)
-				ndl.Par = &fa.NeedlePar
-				err = ndl.Align()
-				if err != nil {
-					panic(fmt.Sprintf("Failed to align query %s againt ref %s, error: %s.", fa.Queries[qi].Id, hitId, err.Error()))
-				}
+	// Terminate the thread
+	threadChan <- err
+}
 
-				if ndl.Rst.GetSimilarityPct() > bestHitSim {
-					bestHitId = hitId
-					bestHitDesc = hitSeq.Desc
-					bestHitLen = hitSeq.Length()
-					bestHitSim = ndl.Rst.GetSimilarityPct()
-					bestHitNum = chkhit + 1
-				}
+// Search and validate an annotation for the query qi in the current DB,
+// the query is marked as checked together with its result
+func (fa *Fannot) annotate(qi int) error {
+	/* First step: homology search */
+	hits, err := fa.search(qi)
+	if err != nil {
+		return fmt.Errorf("Failed to search query %s in the reference DB %s: %w", fa.Queries[qi].Id, fa.DBs[fa.DBi].Id, err)
+	}
 
-				chkhit++
-				if chkhit >= fa.FaPar.Nbh_chk {
-					break HITS
-				}
-			}
+	// Nothing to do without hit
+	if len(hits) == 0 {
+		fa.lock.Lock()
+		fa.Checked[qi] = true
+		fa.lock.Unlock()
+		return nil
+	}
 
-			// Validate the best Hit
-			bestHitLenRatio := getMinLengthRatio(bestHitLen, fa.Queries[qi].Length())
-		CHECK:
-			for _, rule := range fa.FaPar.Rules {
-				if bestHitSim >= rule.Min_sim && bestHitLenRatio >= rule.Min_lra {
-					bestHitStatus = rule.Hit_sta
-					bestHitCanOwr = rule.Ovr_wrt
-					bestHitCpyGn = rule.Cpy_gen
-					bestHitPre = rule.Pre_ann
-					break CHECK
-				}
+	chkhit := 0 // Number if hit checked
+	bestHit := -1
+	bestHitId := "NULL"
+	var bestHitMeta *refdb.Meta
+	bestHitSim := 0.0
+	bestHitLenRatio := 0.0
+	bestHitNum := 0
+	bestHitStatus := 0
+	bestHitCanOwr := false
+	bestHitCpyGn := true
+	bestHitCpyOnt := false
+	bestHitPre := ""
+	bestHitRule := ""
+	bestHitTie := "" // Criterion that broke a similarity tie
+	examined := make([]HitInfo, 0, len(hits))
+	skipped := make([]HitInfo, 0)
+	attrs := make([]HitAttrs, 0, len(hits))
+	metas := make([]*refdb.Meta, 0, len(hits))
+	ruleSet, nbhChk, rules := fa.FaPar.GetRuleSet(fa.DBs[fa.DBi].Id)
+
+HITS:
+	for _, hit := range hits {
+		// For each hit, compute the global alignment and extract the similarity
+		hitId := hit.Id
+		hitSeq, err := fa.dbEntry(hitId)
+		if err != nil {
+			return err
+		}
+		meta, err := fa.hitMeta(hitSeq)
+		if errors.Is(err, refdb.ErrMalformedDesc) {
+			// A corrupted description of an older DB only discards its hit
+			skipped = append(skipped, HitInfo{DB: fa.DBs[fa.DBi].Id, Id: hitId, Skipped: HIT_UNPARSABLE})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read the metadata of the hit %s in the reference DB %s: %w", hitId, fa.DBs[fa.DBi].Id, err)
+		}
+		aln, err := fa.Aligner.Align(fa.Queries[qi], hitSeq)
+		if err != nil {
+			return fmt.Errorf("Failed to align query %s against ref %s: %w", fa.Queries[qi].Id, hitId, err)
+		}
+		hitSim := aln.Similarity
+		hitStats := HitStats{
+			Similarity:  hitSim,
+			Identity:    aln.Identity,
+			LengthRatio: getMinLengthRatio(hitSeq.Length(), fa.Queries[qi].Length()),
+			QueryCov:    aln.QueryCov,
+			TargetCov:   aln.TargetCov,
+			Evalue:      hit.Evalue,
+			BitScore:    hit.BitScore,
+		}
+		examined = append(examined, HitInfo{DB: fa.DBs[fa.DBi].Id, Id: hitId, Rank: chkhit + 1, HitStats: hitStats})
+		attrs = append(attrs, newHitAttrs(hitStats, meta, &fa.DBs[fa.DBi]))
+		metas = append(metas, meta)
+
+		// Select the most similar hit (see the tie-break chain)
+		if bestHit < 0 {
+			if hitSim > 0.0 {
+				bestHit = chkhit
 			}
+		} else if ok, _ := preferHit(fa.FaPar.Tie_brk, examined, attrs, chkhit, bestHit); ok {
+			bestHit = chkhit
+		}
 
-			// Get the annotation if the best hit is good enough
-			hitIsQuery := false
-			if fa.DBs[fa.DBi].Equal && bestHitSim == 100.0 {
-				hitIsQuery = true
-			}
-			if bestHitStatus > 0 {
-				// If no annotation yet
-				if !fa.Finished[qi] {
-					// Set an annotation to this protein
-					fa.Finished[qi] = true
-					fa.Results[qi] = *ParseHitDesc(bestHitDesc, bestHitId, fa.DBs[fa.DBi].Id, bestHitStatus, bestHitPre, hitIsQuery, fa.DBs[fa.DBi].Reviewed, fa.DBs[fa.DBi].GeneName)
-					fa.Results[qi].HitSim = bestHitSim
-					fa.Results[qi].HitLR = bestHitLenRatio
-					fa.Results[qi].HitNum = bestHitNum
-					if fa.Results[qi].CopyGID {
-						// Reset gene name copy
-						fa.Results[qi].CopyGID = bestHitCpyGn
-					}
-				} else if fa.DBs[fa.DBi].OverWrite && bestHitCanOwr {
-					// The current DB allows overwrite
-					// An overwrite is possible only if the stored
-					// annotation is "similar" and the new hit is
-					// better.
-					if fa.Results[qi].Status == 1 {
-						if bestHitSim > fa.Results[qi].HitSim && bestHitLenRatio > fa.Results[qi].HitLR {
-							fa.Results[qi] = *ParseHitDesc(bestHitDesc, bestHitId, fa.DBs[fa.DBi].Id, bestHitStatus, bestHitPre, hitIsQuery, fa.DBs[fa.DBi].Reviewed, fa.DBs[fa.DBi].GeneName)
-							fa.Results[qi].HitSim = bestHitSim
-							fa.Results[qi].HitLR = bestHitLenRatio
-							fa.Results[qi].HitNum = bestHitNum
-							fa.Results[qi].HitOW = true
-							if fa.Results[qi].CopyGID {
-								// Reset gene name copy
-								fa.Results[qi].CopyGID = bestHitCpyGn
-							}
-						}
-					}
+		chkhit++
+		if chkhit >= nbhChk {
+			break HITS
+		}
+	}
+
+	// Select the rule satisfied by the best hit (all the rule thresholds
+	// and conditions must be satisfied). In consensus mode, the best hit
+	// is the most similar hit of the majority product.
+	bestRule := -1
+	appRule := -1 // Rule applied to the hit (lowered on conflict, see Cns_cfl)
+	cnsSupport := ""
+	cnsNote := ""
+	if fa.FaPar.Cns_mod {
+		cns, err := newConsensus(examined, attrs, metas, rules, fa.FaPar.Tie_brk)
+		if err != nil {
+			return fmt.Errorf("Failed to build the consensus (%s) for query %s: %w", ruleSet, fa.Queries[qi].Id, err)
+		}
+		if cns.Hit >= 0 {
+			bestHit = cns.Hit
+			bestRule = cns.Rule
+			bestHitTie = cns.TieBreak
+			appRule = bestRule
+			cnsSupport = cns.String()
+			if cns.HasConflict() {
+				conflicts := strings.Join(cns.Conflicts, ", ")
+				if fa.FaPar.Cns_cfl == CNS_LOWER && bestRule+1 < len(rules) {
+					appRule++
+					cnsNote = fmt.Sprintf(", status lowered to rule %d by conflicting annotations: %s", appRule+1, conflicts)
+				} else {
+					cnsNote = ", conflicting annotations: " + conflicts
 				}
 			}
+		}
+	} else if bestHit >= 0 {
+		cands := make([]int, len(examined))
+		for i := range cands {
+			cands[i] = i
+		}
+		bestHitTie = decidingTie(fa.FaPar.Tie_brk, examined, attrs, bestHit, cands)
+		bestRule, err = firstRule(rules, &attrs[bestHit])
+		if err != nil {
+			return fmt.Errorf("Failed to evaluate the rule %d (%s) for query %s: %w", bestRule+1, ruleSet, fa.Queries[qi].Id, err)
+		}
+		appRule = bestRule
+	}
+	if bestRule >= 0 {
+		bestHitId = examined[bestHit].Id
+		bestHitMeta = metas[bestHit]
+		bestHitSim = examined[bestHit].Similarity
+		bestHitLenRatio = examined[bestHit].LengthRatio
+		bestHitNum = examined[bestHit].Rank
+		rule := rules[appRule]
+		bestHitCanOwr, err = rule.CanOverwrite(&attrs[bestHit])
+		if err == nil {
+			bestHitCpyGn, err = rule.CopyGene(&attrs[bestHit])
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to evaluate the rule %d (%s) for query %s: %w", appRule+1, ruleSet, fa.Queries[qi].Id, err)
+		}
+		bestHitStatus = rule.Hit_sta
+		bestHitPre = rule.Pre_ann
+		bestHitCpyOnt = rule.Cpy_ont
+		// Record the rule satisfied by the hit (even if lowered)
+		bestHitRule = fmt.Sprintf("rule %d: %s", bestRule+1, rules[bestRule].String())
+	}
 
+	// Check that the query is the reciprocal best hit of the reference
+	// before copying its gene name (the reverse search is skipped if the
+	// annotation cannot be stored)
+	rbh := ""
+	rbhHit := ""
+	fa.lock.Lock()
+	store := bestHitStatus > 0 && fa.canStore(qi, bestHitSim, bestHitLenRatio, bestHitCanOwr)
+	fa.lock.Unlock()
+	if fa.QueryDB != nil && store && bestHitCpyGn && fa.DBs[fa.DBi].GeneName && attrs[bestHit].Name != "" {
+		ok, best, err := fa.reciprocal(qi, bestHitId)
+		if err != nil {
+			return err
 		}
-		// Else do nothing
+		rbhHit = best
+		if ok {
+			rbh = RBH_CONFIRMED
+		} else {
+			rbh = RBH_REJECTED
+			bestHitCpyGn = false
+		}
+	}
 
-		// Remove the query
-		blt.ResetQuery()
+	// Get the annotation if the best hit is good enough
+	sel := hitSelection{
+		Meta:      bestHitMeta,
+		DB:        &fa.DBs[fa.DBi],
+		Status:    bestHitStatus,
+		Pre:       bestHitPre,
+		IsQuery:   fa.DBs[fa.DBi].Equal && bestHitSim == 100.0,
+		Sim:       bestHitSim,
+		LenRatio:  bestHitLenRatio,
+		Num:       bestHitNum,
+		Rule:      bestHitRule,
+		RuleSet:   ruleSet,
+		Consensus: cnsSupport,
+		TieBreak:  bestHitTie,
+		Note:      cnsNote,
+		Rbh:       rbh,
+		RbhHit:    rbhHit,
+		CpyGn:     bestHitCpyGn,
+		CpyOnt:    bestHitCpyOnt,
+	}
+	fa.lock.Lock()
+	defer fa.lock.Unlock()
+
+	// Keep track of the examined hits (results may be replaced below),
+	// a checkpoint cannot save them without the checked flag
+	allHits := append(fa.Results[qi].Hits, examined...)
+	allHits = append(allHits, skipped...)
+	defer func() {
+		fa.Results[qi].Hits = allHits
+		fa.Checked[qi] = true
+	}()
+
+	if store {
+		// Set an annotation to this protein or overwrite the current one
+		ow := fa.Finished[qi]
+		fa.Finished[qi] = true
+		fa.Results[qi] = *sel.Result()
+		fa.Results[qi].HitOW = ow
 	}
 
-	// Terminate the thread
-	threadChan <- 1
+	return nil
+}
+
+// Indicate if an annotation from the current DB can be stored for a
+// query: the query has no annotation yet, or the current DB allows
+// overwrite, the stored annotation is "similar" and the new hit is
+// better (the lock must be held)
+func (fa *Fannot) canStore(qi int, sim, lr float64, canOwr bool) bool {
+	if !fa.Finished[qi] {
+		return true
+	}
+	return fa.DBs[fa.DBi].OverWrite && canOwr && fa.Results[qi].Status == 1 &&
+		sim > fa.Results[qi].HitSim && lr > fa.Results[qi].HitLR
+}
+
+// Hit selected to annotate a query and provenance of the selection
+type hitSelection struct {
+	Meta      *refdb.Meta
+	DB        *refdb.Refdb
+	Status    int
+	Pre       string // Prefix of the note (see Rule.Pre_ann)
+	IsQuery   bool   // The hit is the query itself (see Refdb.Equal)
+	Sim       float64
+	LenRatio  float64
+	Num       int
+	Rule      string
+	RuleSet   string
+	Consensus string
+	TieBreak  string
+	Note      string // Added to the note of the reference
+	Rbh       string
+	RbhHit    string
+	CpyGn     bool // The rule allows the copy of the gene name
+	CpyOnt    bool // The rule transfers the ontology of the reference
+}
+
+// Build the annotation of a query from the selected hit
+func (sel *hitSelection) Result() *FAResult {
+	far := ParseHitMeta(sel.Meta, sel.DB.Id, sel.Status, sel.Pre, sel.IsQuery, sel.DB.Reviewed, sel.DB.GeneName)
+	far.HitSim = sel.Sim
+	far.HitLR = sel.LenRatio
+	far.HitNum = sel.Num
+	far.Rule = sel.Rule
+	far.RuleSet = sel.RuleSet
+	far.Consensus = sel.Consensus
+	far.TieBreak = sel.TieBreak
+	far.Note += sel.Note
+	far.Rbh = sel.Rbh
+	far.RbhHit = sel.RbhHit
+	if sel.CpyOnt {
+		far.GoTerms, far.EC, far.Pathways = newOntology(sel.Meta)
+	}
+	if far.CopyGID {
+		// Reset gene name copy
+		far.CopyGID = sel.CpyGn
+	}
+	return far
 }
 
 func (fa *Fannot) AddIpsAnnot() {
@@ -377,7 +835,7 @@ func (fa *Fannot) AddIpsAnnot() {
 		ips, ok := fa.Ips.Data[gid]
 		if ok {
 			// Sort IPS keys in order to avoid random IPS order
-			ipsids := make([]string, len(ips.KeyValue))
+			ipsids := make([]string, 0, len(ips.KeyValue))
 			for ipsid := range ips.KeyValue {
 				ipsids = append(ipsids, ipsid)
 			}
@@ -389,7 +847,7 @@ func (fa *Fannot) AddIpsAnnot() {
 			}
 
 			// If no homology found, then add IpsAnnot to /note qualifier
-			if fa.Results[qi].Status == 0 {
+			if !fa.Results[qi].HasHit() {
 				fa.Results[qi].Note += ", InterProScan predictions: " + strings.Join(fa.Results[qi].IpsAnnot, "; ")
 			}
 		}
@@ -0,0 +1,114 @@
+package fannot
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hdevillers/go-fannot/ips"
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+// Shared fixture of the FindFunction tests: fake tools, queries and
+// reference entries of a single test DB
+
+// In-memory searcher: return predefined hits for each query ID
+type fakeSearcher struct {
+	hits map[string][]Candidate
+}
+
+func (fs *fakeSearcher) Search(q seq.Seq, db *refdb.Refdb) ([]Candidate, error) {
+	return fs.hits[q.Id], nil
+}
+
+// In-memory aligner: return predefined similarities for each query/target pair
+type fakeAligner struct {
+	sim map[string]float64
+}
+
+func (fl *fakeAligner) Align(q, t seq.Seq) (*Alignment, error) {
+	s := fl.sim[q.Id+"/"+t.Id]
+	return &Alignment{Similarity: s, Identity: s, QueryCov: 100.0, TargetCov: 100.0}, nil
+}
+
+func newTestSeq(id, desc, s string) seq.Seq {
+	ns := seq.NewSeq(id)
+	ns.Desc = desc
+	ns.Sequence = []byte(s)
+	return *ns
+}
+
+// Create n queries (q1, q2...) with the same sequence
+func newTestQueries(n int) []seq.Seq {
+	queries := make([]seq.Seq, n)
+	for i := range queries {
+		queries[i] = newTestSeq(fmt.Sprintf("q%d", i+1), "", "MKVLAGT")
+	}
+	return queries
+}
+
+// Create a Fannot object with a single reference DB and fake tools
+func newTestFannot(queries []seq.Seq, entries []seq.Seq, s Searcher, a Aligner) *Fannot {
+	var fa Fannot
+	fa.Queries = queries
+	fa.NQueries = len(queries)
+	fa.DBs = []refdb.Refdb{{Id: "testdb", Reviewed: true, GeneName: true}}
+	fa.DBi = 0
+	fa.DBEntries = make(map[string]seq.Seq)
+	for _, e := range entries {
+		fa.DBEntries[e.Id] = e
+	}
+	fa.Finished = make([]bool, fa.NQueries)
+	fa.Checked = make([]bool, fa.NQueries)
+	fa.Results = make([]FAResult, fa.NQueries)
+	fa.Ips = *ips.NewIps()
+	fa.SetParam(NewParam())
+	fa.Searcher = s
+	fa.Aligner = a
+	return &fa
+}
+
+// Store the metadata of the reference entries (read instead of the
+// FASTA descriptions)
+func setTestMeta(t *testing.T, fa *Fannot, metas []refdb.Meta) {
+	dir := t.TempDir()
+	fa.DBs[0].Metadata = dir + "/" + refdb.META_PATH
+	fa.DBs[0].MetaIndex = dir + "/" + refdb.META_INDEX_PATH
+	fa.DBs[0].EntryIndex = dir + "/" + refdb.ENTRY_INDEX_PATH
+	mw, err := refdb.NewMetaWriter(fa.DBs[0].Metadata, fa.DBs[0].MetaIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range metas {
+		if err == nil {
+			err = mw.Write(&metas[i])
+		}
+	}
+	if err == nil {
+		err = mw.Flush()
+	}
+	mw.Close()
+	if err == nil {
+		err = refdb.WriteEntryIndex("", fa.DBs[0].MetaIndex, fa.DBs[0].EntryIndex)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	fa.DBMeta, err = fa.DBs[0].OpenMeta()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fa.DBMeta.Close() })
+}
+
+// Run FindFunction over all queries
+func runTestFannot(fa *Fannot) error {
+	queryChan := make(chan int)
+	threadChan := make(chan error)
+	go fa.FindFunction(queryChan, threadChan)
+	for i := 0; i < fa.NQueries; i++ {
+		queryChan <- i
+	}
+	close(queryChan)
+	return <-threadChan
+}
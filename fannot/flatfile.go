@@ -0,0 +1,270 @@
+package fannot
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hdevillers/go-fannot/flatfile"
+	"github.com/hdevillers/go-fannot/gff"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+// Default database tag of the feature table IDs (gnl|<db>|<id>)
+const (
+	D_TBL_DB string = "fannot"
+)
+
+// INSDC names of the UniProt databases (db_xref qualifier)
+const (
+	INSDC_SWISSPROT string = "UniProtKB/Swiss-Prot"
+	INSDC_TREMBL    string = "UniProtKB/TrEMBL"
+)
+
+// Return the INSDC inference of the annotation ("" without hit)
+func (far *FAResult) Inference() string {
+	if !far.HasHit() {
+		return ""
+	}
+	return "similar to AA sequence:" + DBXREF_UNIPROT + ":" + far.GeneID
+}
+
+// Return the INSDC cross-references of the annotation
+func (far *FAResult) DbXrefs() []string {
+	xrefs := make([]string, 0)
+	if far.HasHit() {
+		if far.Reviewed {
+			xrefs = append(xrefs, INSDC_SWISSPROT+":"+far.GeneID)
+		} else {
+			xrefs = append(xrefs, INSDC_TREMBL+":"+far.GeneID)
+		}
+	}
+	for _, id := range far.IpsId {
+		xrefs = append(xrefs, DBXREF_INTERPRO+":"+id)
+	}
+	return xrefs
+}
+
+// Index queries by ID
+func (fa *Fannot) queryIndex() map[string]int {
+	qids := make(map[string]int)
+	for qi := 0; qi < fa.NQueries; qi++ {
+		qids[fa.Queries[qi].Id] = qi
+	}
+	return qids
+}
+
+// Return the index of the query corresponding to a transcript (-1 if none)
+func findTranscriptQuery(g *gff.Gene, t *gff.Transcript, qids map[string]int) int {
+	ids := []string{t.Id, t.ProteinId, t.CdsId}
+	if len(g.Transcripts) == 1 {
+		ids = append(ids, g.Id)
+	}
+	for _, id := range ids {
+		if qi, ok := qids[id]; ok && id != "" {
+			return qi
+		}
+	}
+	return -1
+}
+
+// Return the result of a transcript (default result if not a query)
+func (fa *Fannot) transcriptResult(g *gff.Gene, t *gff.Transcript, qids map[string]int) *FAResult {
+	if qi := findTranscriptQuery(g, t, qids); qi >= 0 {
+		return &fa.Results[qi]
+	}
+	return NewFAResult(&fa.FaPar, t.Id)
+}
+
+// Build the INSDC features (gene, mRNA and CDS) of a gene model,
+// transcript and protein IDs are added if db is set (feature table)
+func (fa *Fannot) geneFeatures(g *gff.Gene, qids map[string]int, db string) []flatfile.Feature {
+	comp := g.Strand == "-"
+	gene := flatfile.NewFeature("gene", comp)
+	gene.AddSegment(g.Start, g.End)
+	feats := make([]flatfile.Feature, 0)
+
+	for _, t := range g.Transcripts {
+		far := fa.transcriptResult(g, t, qids)
+		pid := t.ProteinId
+		if pid == "" {
+			pid = t.Id
+		}
+		if far.CopyGID && len(gene.Qualifiers) == 0 {
+			gene.AddQualifier("gene", far.Name)
+		}
+
+		// mRNA (only if exons are defined)
+		if len(t.Exons) > 0 {
+			mrna := flatfile.NewFeature("mRNA", comp)
+			for _, e := range t.Exons {
+				mrna.AddSegment(e.Start, e.End)
+			}
+			if far.CopyGID {
+				mrna.AddQualifier("gene", far.Name)
+			}
+			mrna.AddQualifier("product", far.Product)
+			if db != "" {
+				// table2asn requires both IDs on the mRNA and the CDS
+				mrna.AddQualifier("protein_id", "gnl|"+db+"|"+pid)
+				mrna.AddQualifier("transcript_id", "gnl|"+db+"|"+t.Id)
+			}
+			feats = append(feats, *mrna)
+		}
+
+		// CDS
+		if len(t.Cds) > 0 {
+			cds := flatfile.NewFeature("CDS", comp)
+			for _, c := range t.Cds {
+				cds.AddSegment(c.Start, c.End)
+			}
+			if far.CopyGID {
+				cds.AddQualifier("gene", far.Name)
+			}
+			if phase := g.CdsPhase(t); phase > 0 {
+				cds.AddQualifier("codon_start", strconv.Itoa(phase+1))
+			}
+			cds.AddQualifier("product", far.Product)
+			if far.Note != far.Product {
+				cds.AddQualifier("note", far.Note)
+			}
+			for _, ec := range far.EC {
+				cds.AddQualifier("EC_number", ec)
+			}
+			for _, pw := range far.Pathways {
+				cds.AddQualifier("note", "pathway: "+pw)
+			}
+			for _, x := range far.DbXrefs() {
+				cds.AddQualifier("db_xref", x)
+			}
+			for _, t := range far.GoTerms {
+				q, ok := goQualifiers[t.Aspect]
+				if !ok {
+					continue
+				}
+				if db != "" {
+					cds.AddQualifier(q, t.Term+"|"+strings.TrimPrefix(t.Id, "GO:")+"||"+t.Evidence)
+				} else {
+					// Not INSDC qualifiers (rejected in flat files)
+					cds.AddQualifier("note", q+": "+t.Term+" ["+t.Id+"]")
+				}
+			}
+			if inf := far.Inference(); inf != "" {
+				cds.AddQualifier("inference", inf)
+			}
+			if db != "" {
+				cds.AddQualifier("protein_id", "gnl|"+db+"|"+pid)
+				if len(t.Exons) > 0 {
+					cds.AddQualifier("transcript_id", "gnl|"+db+"|"+t.Id)
+				}
+			}
+			feats = append(feats, *cds)
+		}
+	}
+
+	return append([]flatfile.Feature{*gene}, feats...)
+}
+
+// Load the gene models and group them by sequence (sorted by position),
+// sequence IDs are also returned in the file order
+func loadSeqGenes(models string) (map[string][]*gff.Gene, []string, error) {
+	genes, err := gff.LoadGenes(models)
+	if err != nil {
+		return nil, nil, err
+	}
+	bySeq := make(map[string][]*gff.Gene)
+	seqids := make([]string, 0)
+	for _, g := range genes {
+		if _, ok := bySeq[g.Seqid]; !ok {
+			seqids = append(seqids, g.Seqid)
+		}
+		bySeq[g.Seqid] = append(bySeq[g.Seqid], g)
+	}
+	for _, gs := range bySeq {
+		sort.SliceStable(gs, func(i, j int) bool { return gs[i].Start < gs[j].Start })
+	}
+	return bySeq, seqids, nil
+}
+
+// Write the annotated genome sequences (FASTA) with their gene models
+// (GFF3) in the EMBL or GenBank flat file format
+func (fa *Fannot) WriteFlatFile(genome, models, out, format, organism string) error {
+	bySeq, _, err := loadSeqGenes(models)
+	if err != nil {
+		return err
+	}
+	qids := fa.queryIndex()
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fw := bufio.NewWriter(f)
+
+	// Write records while reading the genome (keep the first error)
+	var werr error
+	err = readFasta(genome, func(s seq.Seq) {
+		if werr != nil {
+			return
+		}
+		rec := flatfile.NewRecord(s.Id, s.Sequence)
+		if s.Desc != "" {
+			rec.Desc = s.Desc
+		}
+		rec.Organism = organism
+		for _, g := range bySeq[s.Id] {
+			rec.Features = append(rec.Features, fa.geneFeatures(g, qids, "")...)
+		}
+		werr = flatfile.Write(fw, rec, format)
+	})
+	if err != nil {
+		return err
+	}
+	if werr != nil {
+		return werr
+	}
+
+	err = fw.Flush()
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Write the annotated gene models (GFF3) as an NCBI five-column
+// feature table, db is the database tag of the transcript and
+// protein IDs
+func (fa *Fannot) WriteTbl(models, out, db string) error {
+	bySeq, seqids, err := loadSeqGenes(models)
+	if err != nil {
+		return err
+	}
+	qids := fa.queryIndex()
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fw := bufio.NewWriter(f)
+
+	for _, id := range seqids {
+		rec := flatfile.NewRecord(id, nil)
+		for _, g := range bySeq[id] {
+			rec.Features = append(rec.Features, fa.geneFeatures(g, qids, db)...)
+		}
+		err = flatfile.WriteTbl(fw, rec)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = fw.Flush()
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,151 @@
+package fannot
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hdevillers/go-fannot/flatfile"
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+// Create test gene models, genome and results
+func newTestGeneModels(t *testing.T) (*Fannot, string, string) {
+	queries := []seq.Seq{
+		newTestSeq("g1.t1", "", "MKVLAGT"),
+		newTestSeq("g2.t1", "", "MKVLAGT"),
+	}
+	fa := newTestFannot(queries, nil, nil, nil)
+	fa.Finished[0] = true
+	fa.Results[0] = FAResult{
+		Product:  "protein kinase A",
+		Note:     "highly similar to uniprot|P1 Saccharomyces cerevisiae PKA1, kinase",
+		Name:     "PKA1",
+		Status:   2,
+		GeneID:   "P1",
+		CopyGID:  true,
+		RefID:    "testdb",
+		IpsId:    []string{"IPR000719"},
+		Reviewed: true,
+		GoTerms:  []refdb.GoTerm{{Id: "GO:0004672", Aspect: "F", Evidence: "IEA", Term: "protein kinase activity"}},
+	}
+
+	dir := t.TempDir()
+	genome := filepath.Join(dir, "genome.fasta")
+	models := filepath.Join(dir, "genes.gff3")
+	err := ioutil.WriteFile(genome, []byte(">chr1 test chromosome\n"+strings.Repeat("ACGT", 100)+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(models, []byte(strings.Join([]string{
+		"##gff-version 3",
+		"chr1\tmaker\tgene\t200\t290\t.\t-\t.\tID=g2",
+		"chr1\tmaker\tmRNA\t200\t290\t.\t-\t.\tID=g2.t1;Parent=g2",
+		"chr1\tmaker\tCDS\t200\t290\t.\t-\t0\tParent=g2.t1",
+		"chr1\tmaker\tgene\t1\t90\t.\t+\t.\tID=g1",
+		"chr1\tmaker\tmRNA\t1\t90\t.\t+\t.\tID=g1.t1;Parent=g1",
+		"chr1\tmaker\texon\t1\t30\t.\t+\t.\tParent=g1.t1",
+		"chr1\tmaker\texon\t61\t90\t.\t+\t.\tParent=g1.t1",
+		"chr1\tmaker\tCDS\t1\t30\t.\t+\t1\tParent=g1.t1",
+		"chr1\tmaker\tCDS\t61\t90\t.\t+\t0\tParent=g1.t1",
+	}, "\n")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fa, genome, models
+}
+
+func TestWriteFlatFile(t *testing.T) {
+	fa, genome, models := newTestGeneModels(t)
+	out := filepath.Join(t.TempDir(), "genome.gb")
+	err := fa.WriteFlatFile(genome, models, out, flatfile.GENBANK_FORMAT, "Lachancea kluyveri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gb := string(data)
+
+	for _, l := range []string{
+		"DEFINITION  test chromosome.\n",
+		"     gene            1..90\n                     /gene=\"PKA1\"\n",
+		"     mRNA            join(1..30,61..90)\n",
+		"     CDS             join(1..30,61..90)\n                     /gene=\"PKA1\"\n                     /codon_start=2\n",
+		"                     /db_xref=\"UniProtKB/Swiss-Prot:P1\"\n                     /db_xref=\"InterPro:IPR000719\"\n",
+		"                     /inference=\"similar to AA sequence:UniProtKB:P1\"\n",
+		"     CDS             complement(200..290)\n                     /product=\"hypothetical protein\"\n",
+		"                     /note=\"go_function: protein kinase activity [GO:0004672]\"\n",
+	} {
+		if !strings.Contains(gb, l) {
+			t.Errorf("Expected %q in the GenBank file.", l)
+		}
+	}
+	if strings.Contains(gb, "/go_function=") {
+		t.Error("GO qualifiers are not allowed in flat files.")
+	}
+
+	// Genes are sorted by position and unknown proteins have no inference
+	if strings.Index(gb, "gene            1..90") > strings.Index(gb, "complement(200..290)") {
+		t.Error("Genes are not sorted by position.")
+	}
+	if strings.Count(gb, "/inference=") != 1 {
+		t.Error("Expected a single inference qualifier.")
+	}
+}
+
+func TestWriteTbl(t *testing.T) {
+	fa, _, models := newTestGeneModels(t)
+	p := NewParam()
+	p.Unk_ann = "uncharacterized protein"
+	fa.SetParam(p)
+	out := filepath.Join(t.TempDir(), "genome.tbl")
+	err := fa.WriteTbl(models, out, "lab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl := string(data)
+
+	for _, l := range []string{
+		">Feature chr1\n1\t90\tgene\n\t\t\tgene\tPKA1\n",
+		"1\t30\tCDS\n61\t90\n\t\t\tgene\tPKA1\n\t\t\tcodon_start\t2\n\t\t\tproduct\tprotein kinase A\n",
+		"\t\t\tinference\tsimilar to AA sequence:UniProtKB:P1\n",
+		"\t\t\tprotein_id\tgnl|lab|g1.t1\n\t\t\ttranscript_id\tgnl|lab|g1.t1\n",
+		"\t\t\tgo_function\tprotein kinase activity|0004672||IEA\n",
+		"1\t30\tmRNA\n61\t90\n\t\t\tgene\tPKA1\n\t\t\tproduct\tprotein kinase A\n\t\t\tprotein_id\tgnl|lab|g1.t1\n\t\t\ttranscript_id\tgnl|lab|g1.t1\n",
+		"290\t200\tCDS\n\t\t\tproduct\tuncharacterized protein\n",
+	} {
+		if !strings.Contains(tbl, l) {
+			t.Errorf("Expected %q in the feature table.", l)
+		}
+	}
+}
+
+// Genome sequences may be on a single (unwrapped) line
+func TestWriteFlatFileLongLine(t *testing.T) {
+	fa, genome, models := newTestGeneModels(t)
+	err := ioutil.WriteFile(genome, []byte(">chr1\n"+strings.Repeat("ACGT", 30000)+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "genome.embl")
+	err = fa.WriteFlatFile(genome, models, out, flatfile.EMBL_FORMAT, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Sequence 120000 BP;") {
+		t.Error("Expected the whole 120 kb sequence in the EMBL file.")
+	}
+}
@@ -0,0 +1,115 @@
+package fannot
+
+import (
+	"fmt"
+
+	"github.com/hdevillers/go-fannot/gff"
+)
+
+// Database names used in Dbxref and inference attributes
+const (
+	DBXREF_UNIPROT  string = "UniProtKB"
+	DBXREF_INTERPRO string = "InterPro"
+)
+
+// Feature types that receive the functional annotation
+var gffAnnotTypes = map[string]bool{
+	"mRNA": true,
+	"CDS":  true,
+}
+
+// Set the functional annotation attributes of a GFF3 feature
+func (far *FAResult) AnnotateFeature(f *gff.Feature) {
+	f.SetAttribute("product", far.Product)
+	f.SetAttribute("Note", far.Note)
+	if far.CopyGID {
+		f.SetAttribute("Name", far.Name)
+		f.SetAttribute("gene", far.Name)
+	}
+
+	// Cross-references: reference hit and InterPro predictions
+	xrefs := make([]string, 0)
+	if far.HasHit() {
+		xrefs = append(xrefs, DBXREF_UNIPROT+":"+far.GeneID)
+	}
+	for _, id := range far.IpsId {
+		xrefs = append(xrefs, DBXREF_INTERPRO+":"+id)
+	}
+	if len(xrefs) > 0 {
+		f.AddAttribute("Dbxref", xrefs...)
+	}
+
+	// Transferred GO terms, EC numbers and pathways
+	if len(far.GoTerms) > 0 {
+		f.SetAttribute("Ontology_term", far.GoIds()...)
+	}
+	if len(far.EC) > 0 {
+		f.SetAttribute("ec_number", far.EC...)
+	}
+	if len(far.Pathways) > 0 {
+		f.SetAttribute("pathway", far.Pathways...)
+	}
+
+	// Describe how the annotation was obtained
+	if far.HasHit() {
+		f.SetAttribute("inference", fmt.Sprintf("%s (%s %s)", far.Inference(), far.RefID, far.Rule))
+	}
+}
+
+// Return the index of the query described by a GFF3 feature (-1 if none)
+func (fa *Fannot) findFeatureQuery(f *gff.Feature, qids map[string]int) int {
+	keys := []string{"ID", "protein_id", "Name"}
+	if f.Type == "CDS" {
+		keys = append(keys, "Parent")
+	}
+	for _, k := range keys {
+		for _, v := range f.GetAttribute(k) {
+			if qi, ok := qids[v]; ok {
+				return qi
+			}
+		}
+	}
+	return -1
+}
+
+// Copy the input GFF3 file of gene models into the output file
+// adding the functional annotation to the mRNA and CDS features
+func (fa *Fannot) WriteGff(in, out string) error {
+	qids := fa.queryIndex()
+
+	gr, err := gff.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	gw, err := gff.NewWriter(out)
+	if err != nil {
+		return err
+	}
+
+	for gr.Next() {
+		// Lines that are not annotated are copied as is
+		f := gr.Feature()
+		qi := -1
+		if f != nil && gffAnnotTypes[f.Type] {
+			qi = fa.findFeatureQuery(f, qids)
+		}
+		if qi >= 0 {
+			fa.Results[qi].AnnotateFeature(f)
+			err = gw.WriteFeature(f)
+		} else {
+			err = gw.WriteLine(gr.Line())
+		}
+		if err != nil {
+			gw.Close()
+			return err
+		}
+	}
+	if gr.Err() != nil {
+		gw.Close()
+		return fmt.Errorf("Failed to read the GFF3 file %s: %w", in, gr.Err())
+	}
+
+	return gw.Close()
+}
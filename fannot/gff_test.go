@@ -0,0 +1,86 @@
+package fannot
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hdevillers/go-seq/seq"
+)
+
+func TestWriteGff(t *testing.T) {
+	queries := []seq.Seq{
+		newTestSeq("g1.t1", "", "MKVLAGT"),
+		newTestSeq("g2.t1", "", "MKVLAGT"),
+	}
+	fa := newTestFannot(queries, nil, nil, nil)
+	fa.Results[0] = FAResult{
+		Product:  "protein kinase A",
+		Note:     "similar to uniprot|P1 Saccharomyces cerevisiae PKA1, kinase",
+		Name:     "PKA1",
+		Status:   2,
+		GeneID:   "P1",
+		CopyGID:  true,
+		RefID:    "testdb",
+		IpsId:    []string{"IPR000719"},
+		Rule:     "rule 1: similarity >= 80.00% and length ratio >= 0.80",
+		Reviewed: true,
+	}
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.gff3")
+	out := filepath.Join(dir, "out.gff3")
+	input := strings.Join([]string{
+		"##gff-version 3",
+		"chr1\tmaker\tgene\t1\t90\t.\t+\t.\tID=g1",
+		"chr1\tmaker\tmRNA\t1\t90\t.\t+\t.\tID=g1.t1;Parent=g1",
+		"chr1\tmaker\tCDS\t1\t90\t.\t+\t0\tID=g1.t1.cds;Parent=g1.t1",
+		"chr1\tmaker\tmRNA\t200\t290\t.\t-\t.\tID=g2.t1;Parent=g2",
+		"##FASTA",
+		">chr1",
+		"ACGT",
+	}, "\n") + "\n"
+	err := ioutil.WriteFile(in, []byte(input), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fa.WriteGff(in, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("Expected 8 lines, found %d.", len(lines))
+	}
+
+	// Gene and FASTA lines are unchanged
+	if lines[1] != "chr1\tmaker\tgene\t1\t90\t.\t+\t.\tID=g1" || lines[7] != "ACGT" {
+		t.Error("Lines without annotation have been modified.")
+	}
+
+	// mRNA and CDS of the annotated query
+	for _, l := range lines[2:4] {
+		for _, a := range []string{
+			"product=protein kinase A",
+			"Name=PKA1",
+			"gene=PKA1",
+			"Dbxref=UniProtKB:P1,InterPro:IPR000719",
+			"inference=similar to AA sequence:UniProtKB:P1 (testdb rule 1: similarity >%3D 80.00%25 and length ratio >%3D 0.80)",
+		} {
+			if !strings.Contains(l, a) {
+				t.Errorf("Expected %s in %s.", a, l)
+			}
+		}
+	}
+
+	// Unknown function: no cross-reference nor inference
+	if !strings.Contains(lines[4], "product="+UNKNOWN_FUNC) || strings.Contains(lines[4], "inference=") {
+		t.Errorf("Unexpected annotation of an unknown protein: %s.", lines[4])
+	}
+}
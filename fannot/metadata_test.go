@@ -0,0 +1,157 @@
+package fannot
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-fannot/swiss"
+	"github.com/hdevillers/go-seq/seq"
+	"github.com/hdevillers/go-seq/seqio/fasta"
+)
+
+func TestFindFunctionUnparsableDesc(t *testing.T) {
+	queries := []seq.Seq{newTestSeq("q1", "", "MKVLAGT")}
+	// The description of P1 contains a "::" (older DBs)
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::B subunit::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+		newTestSeq("P2", "Protein kinase B::PKB1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P1"}, {Id: "P2"}}}}
+	a := &fakeAligner{map[string]float64{"q1/P1": 95.0, "q1/P2": 90.0}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	err := runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// P1 is skipped, the query is annotated from P2
+	r := fa.Results[0]
+	if r.Product != "protein kinase B" || r.Name != "PKB1" || r.HitNum != 1 {
+		t.Errorf("Expected the annotation of P2, found %s (%s, hit %d).", r.Product, r.Name, r.HitNum)
+	}
+	if len(r.Hits) != 2 || r.Hits[0].Id != "P2" || r.Hits[1].Id != "P1" || r.Hits[1].Skipped != HIT_UNPARSABLE || r.Hits[1].Rank != 0 {
+		t.Errorf("Expected P1 to be recorded as skipped, found %+v.", r.Hits)
+	}
+}
+
+func TestFindFunctionSubName(t *testing.T) {
+	queries := newTestQueries(1)
+	entries := []seq.Seq{newTestSeq("A0A1D8PD39", "", "MKVLAGT")}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "A0A1D8PD39"}}}}
+	a := &fakeAligner{map[string]float64{"q1/A0A1D8PD39": 90.0}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	setTestMeta(t, fa, []refdb.Meta{{
+		Access:   "A0A1D8PD39",
+		Desc:     "Protein kinase",
+		Name:     "CKA2",
+		Locus:    "orf19.3530",
+		Organism: "Candida albicans (strain SC5314 / ATCC MYA-2876) (Yeast).",
+		Evidence: 3,
+		DescType: swiss.DESC_SUBNAME,
+	}})
+	err := runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Submitted names are labelled as putative
+	r := fa.Results[0]
+	if r.Product != "putative protein kinase" || r.DescType != swiss.DESC_SUBNAME || !strings.Contains(r.Note, ", submitted name") {
+		t.Errorf("Unexpected annotation from a submitted name: %s (%s).", r.Product, r.Note)
+	}
+}
+
+func TestFindFunctionMetadata(t *testing.T) {
+	queries := newTestQueries(1)
+	entries := []seq.Seq{newTestSeq("P1", "Protein kinase A", "MKVLAGT")}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P1"}}}}
+	a := &fakeAligner{map[string]float64{"q1/P1": 95.0}}
+
+	// The metadata are read from the store, not from the FASTA description
+	fa := newTestFannot(queries, entries, s, a)
+	setTestMeta(t, fa, []refdb.Meta{{Access: "P1", Desc: "Protein kinase A::B subunit", Name: "PKA1", Organism: "Saccharomyces cerevisiae"}})
+
+	err := runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := fa.Results[0]
+	if r.Product != "protein kinase A::B subunit" || r.Name != "PKA1" || r.Organism != "Saccharomyces cerevisiae" {
+		t.Errorf("Unexpected annotation from the metadata store: %s, %s, %s.", r.Product, r.Name, r.Organism)
+	}
+}
+
+func TestFindFunctionIndexedFasta(t *testing.T) {
+	queries := newTestQueries(2)
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P1"}}, "q2": {{Id: "P2"}}}}
+	a := &fakeAligner{map[string]float64{"q1/P1": 95.0}}
+
+	// Only the indexes are loaded, the hits are read from the files
+	dir := t.TempDir()
+	fa := newTestFannot(queries, nil, s, a)
+	rdb := &fa.DBs[0]
+	rdb.Fasta = dir + "/" + refdb.FASTA_PATH
+	rdb.FastaIndex = dir + "/" + refdb.FASTA_INDEX_PATH
+	rdb.Metadata = dir + "/" + refdb.META_PATH
+	rdb.MetaIndex = dir + "/" + refdb.META_INDEX_PATH
+	rdb.EntryIndex = dir + "/" + refdb.ENTRY_INDEX_PATH
+	f, err := os.Create(rdb.Fasta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = fasta.NewWriter(bufio.NewWriter(f)).Write(newTestSeq("P1", "Protein kinase A", "MKVLAGT"))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = refdb.IndexFasta(rdb.Fasta, rdb.FastaIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw, err := refdb.NewMetaWriter(rdb.Metadata, rdb.MetaIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mw.Write(&refdb.Meta{Access: "P1", Desc: "Protein kinase A", Name: "PKA1", Organism: "Saccharomyces cerevisiae"})
+	if err == nil {
+		err = mw.Flush()
+	}
+	mw.Close()
+	if err == nil {
+		err = refdb.WriteEntryIndex(rdb.FastaIndex, rdb.MetaIndex, rdb.EntryIndex)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	fa.DBi = 0
+	err = fa.LoadDBEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fa.DBSeqs.Close()
+	defer fa.DBMeta.Close()
+	if len(fa.DBEntries) != 0 {
+		t.Errorf("Expected no reference sequence in memory, found %d.", len(fa.DBEntries))
+	}
+
+	// The hit of q2 is missing from the DB
+	queryChan := make(chan int)
+	threadChan := make(chan error)
+	go fa.FindFunction(queryChan, threadChan)
+	queryChan <- 0
+	queryChan <- 1
+	close(queryChan)
+	err = <-threadChan
+	if !errors.Is(err, ErrHitMissingFromFasta) {
+		t.Errorf("Expected ErrHitMissingFromFasta for the hit P2, got %v.", err)
+	}
+	if fa.Results[0].Name != "PKA1" || fa.Results[0].Status != 2 {
+		t.Errorf("Unexpected annotation of q1 from the indexed DB: %s (%d).", fa.Results[0].Name, fa.Results[0].Status)
+	}
+}
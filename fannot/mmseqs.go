@@ -0,0 +1,110 @@
+package fannot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+// Default MMseqs2 settings
+const (
+	D_MMSQ_EVALUE  float64 = 0.001
+	D_MMSQ_THREADS int     = 2
+	D_MMSQ_TARGETS int     = 25
+)
+
+// Searcher based on MMseqs2 easy-search (batch mode)
+type MmseqsSearcher struct {
+	Evalue      float64
+	Threads     int
+	MaxTargets  int
+	Sensitivity float64 // Sensitivity (-s), 0 for default
+}
+
+func NewMmseqsSearcher() *MmseqsSearcher {
+	return &MmseqsSearcher{
+		Evalue:     D_MMSQ_EVALUE,
+		Threads:    D_MMSQ_THREADS,
+		MaxTargets: D_MMSQ_TARGETS,
+	}
+}
+
+func (ms *MmseqsSearcher) Search(q seq.Seq, db *refdb.Refdb) ([]Candidate, error) {
+	res, err := ms.SearchBatch([]seq.Seq{q}, db)
+	if err != nil {
+		return nil, err
+	}
+	return res[q.Id], nil
+}
+
+func (ms *MmseqsSearcher) SearchBatch(qs []seq.Seq, db *refdb.Refdb) (map[string][]Candidate, error) {
+	if !db.HasBackend(refdb.MMSEQS_BACKEND) {
+		return nil, fmt.Errorf("No MMseqs2 DB available for the reference DB %s.", db.Id)
+	}
+
+	// MMseqs2 requires a working directory
+	tmp, err := ioutil.TempDir("", "fannot-mmseqs-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	// Write the queries in a temporary FASTA file
+	qf, err := writeTempFasta(qs)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(qf)
+
+	// Run a single search over all queries
+	rf := tmp + "/result.m8"
+	cmd := exec.Command("mmseqs", "easy-search",
+		qf, db.Mmseqsdb, rf, tmp+"/work",
+		"--format-output", "query,target,evalue,bits",
+		"-e", fmt.Sprintf("%g", ms.Evalue),
+		"--threads", strconv.Itoa(ms.Threads),
+		"--max-seqs", strconv.Itoa(ms.MaxTargets),
+		"-v", "1",
+	)
+	if ms.Sensitivity > 0 {
+		cmd.Args = append(cmd.Args, "-s", fmt.Sprintf("%g", ms.Sensitivity))
+	}
+	err = cmd.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse and group results per query
+	f, err := os.Open(rf)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	res, err := parseTabularHits(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rank the hits of each query
+	for qid := range res {
+		rankCandidates(res[qid])
+	}
+
+	return res, nil
+}
+
+// Sort candidates by decreasing bit score (then increasing e-value)
+func rankCandidates(c []Candidate) {
+	sort.SliceStable(c, func(i, j int) bool {
+		if c[i].BitScore != c[j].BitScore {
+			return c[i].BitScore > c[j].BitScore
+		}
+		return c[i].Evalue < c[j].Evalue
+	})
+}
@@ -0,0 +1,30 @@
+package fannot
+
+import (
+	"github.com/hdevillers/go-fannot/refdb"
+)
+
+// Aspects of the GO terms and corresponding feature table qualifiers
+// (table2asn only, they are written as notes in flat files)
+var goQualifiers = map[string]string{
+	"C": "go_component",
+	"F": "go_function",
+	"P": "go_process",
+}
+
+// Copy the GO terms, EC numbers and pathways of a hit
+func newOntology(m *refdb.Meta) ([]refdb.GoTerm, []string, []string) {
+	terms := append(make([]refdb.GoTerm, 0, len(m.GoTerms)), m.GoTerms...)
+	ec := append(make([]string, 0, len(m.EC)), m.EC...)
+	pw := append(make([]string, 0, len(m.Pathways)), m.Pathways...)
+	return terms, ec, pw
+}
+
+// Return the identifiers of the GO terms
+func (far *FAResult) GoIds() []string {
+	ids := make([]string, len(far.GoTerms))
+	for i, t := range far.GoTerms {
+		ids[i] = t.Id
+	}
+	return ids
+}
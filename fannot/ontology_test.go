@@ -0,0 +1,52 @@
+package fannot
+
+import (
+	"testing"
+
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+func TestFindFunctionOntology(t *testing.T) {
+	queries := []seq.Seq{newTestSeq("q1", "", "MKVLAGT"), newTestSeq("q2", "", "MKVLAGT")}
+	entries := []seq.Seq{newTestSeq("P0A6P9", "", "MKVLAGT")}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P0A6P9"}}, "q2": {{Id: "P0A6P9"}}}}
+	a := &fakeAligner{map[string]float64{"q1/P0A6P9": 95.0, "q2/P0A6P9": 60.0}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	setTestMeta(t, fa, []refdb.Meta{{
+		Access:   "P0A6P9",
+		Desc:     "Enolase",
+		Name:     "eno",
+		Locus:    "b2779",
+		Organism: "Escherichia coli (strain K12).",
+		GoTerms: []refdb.GoTerm{
+			{Id: "GO:0000015", Aspect: "C", Evidence: "IDA:EcoCyc", Term: "phosphopyruvate hydratase complex"},
+			{Id: "GO:0004634", Aspect: "F", Evidence: "IDA:EcoCyc", Term: "phosphopyruvate hydratase activity"},
+		},
+		EC:       []string{"4.2.1.11"},
+		Pathways: []string{"Carbohydrate degradation; glycolysis; pyruvate from D-glyceraldehyde 3-phosphate: step 4/5."},
+	}})
+	p, err := NewParamFromJson("../examples/ontology_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fa.SetParam(p)
+	err = runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first rule copies the ontology
+	r := fa.Results[0]
+	if len(r.GoTerms) != 2 || r.GoTerms[1].Id != "GO:0004634" || r.GoTerms[1].Aspect != "F" {
+		t.Errorf("Unexpected GO terms for q1: %v.", r.GoTerms)
+	}
+	if len(r.EC) != 1 || r.EC[0] != "4.2.1.11" || len(r.Pathways) != 1 {
+		t.Errorf("Unexpected EC numbers or pathways for q1: %v, %v.", r.EC, r.Pathways)
+	}
+	r = fa.Results[1]
+	if len(r.GoTerms) != 0 || len(r.EC) != 0 || len(r.Pathways) != 0 {
+		t.Errorf("Expected no ontology for q2, found %v, %v, %v.", r.GoTerms, r.EC, r.Pathways)
+	}
+}
@@ -0,0 +1,146 @@
+package fannot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/hdevillers/go-fannot/refdb"
+)
+
+// InterProScan prediction
+type IpsInfo struct {
+	Id    string `json:"id"`
+	Annot string `json:"annotation"`
+}
+
+// Structured version of a functional annotation result
+type FAResultJson struct {
+	Query       string         `json:"query"`
+	Product     string         `json:"product"`
+	Note        string         `json:"note"`
+	Status      int            `json:"status"`
+	Organism    string         `json:"organism"`
+	RefID       string         `json:"ref_id"`
+	RefLocus    string         `json:"ref_locus"`
+	RefName     string         `json:"ref_name"`
+	CopyName    bool           `json:"copy_name"`
+	Reviewed    bool           `json:"reviewed"`
+	DescType    string         `json:"desc_type,omitempty"`
+	DBID        string         `json:"db_id"`
+	Similarity  float64        `json:"similarity"`
+	LengthRatio float64        `json:"length_ratio"`
+	HitNum      int            `json:"hit_num"`
+	OverWritten bool           `json:"overwritten"`
+	Rule        string         `json:"rule"`
+	RuleSet     string         `json:"rule_set"`
+	Consensus   string         `json:"consensus,omitempty"`
+	TieBreak    string         `json:"tie_break,omitempty"`
+	Rbh         string         `json:"rbh,omitempty"`
+	RbhHit      string         `json:"rbh_hit,omitempty"`
+	ParalogOf   string         `json:"paralog_of,omitempty"`
+	GoTerms     []refdb.GoTerm `json:"go_terms"`
+	EC          []string       `json:"ec"`
+	Pathways    []string       `json:"pathways"`
+	Hits        []HitInfo      `json:"hits"`
+	InterPro    []IpsInfo      `json:"interpro"`
+}
+
+// Return the structured version of the result of the query gid
+func (far *FAResult) Json(gid string) *FAResultJson {
+	fj := FAResultJson{
+		Query:       gid,
+		Product:     far.Product,
+		Note:        far.Note,
+		Status:      far.Status,
+		Organism:    far.Organism,
+		RefID:       far.GeneID,
+		RefLocus:    far.Locus,
+		RefName:     far.Name,
+		CopyName:    far.CopyGID,
+		Reviewed:    far.Reviewed,
+		DescType:    far.DescType,
+		DBID:        far.RefID,
+		Similarity:  far.HitSim,
+		LengthRatio: far.HitLR,
+		HitNum:      far.HitNum,
+		OverWritten: far.HitOW,
+		Rule:        far.Rule,
+		RuleSet:     far.RuleSet,
+		Consensus:   far.Consensus,
+		TieBreak:    far.TieBreak,
+		Rbh:         far.Rbh,
+		RbhHit:      far.RbhHit,
+		ParalogOf:   far.ParalogOf,
+		GoTerms:     far.GoTerms,
+		EC:          far.EC,
+		Pathways:    far.Pathways,
+		Hits:        far.Hits,
+		InterPro:    make([]IpsInfo, 0, len(far.IpsId)),
+	}
+	if fj.Hits == nil {
+		fj.Hits = make([]HitInfo, 0)
+	}
+	if fj.GoTerms == nil {
+		fj.GoTerms = make([]refdb.GoTerm, 0)
+	}
+	if fj.EC == nil {
+		fj.EC = make([]string, 0)
+	}
+	if fj.Pathways == nil {
+		fj.Pathways = make([]string, 0)
+	}
+	for i, id := range far.IpsId {
+		fj.InterPro = append(fj.InterPro, IpsInfo{id, far.IpsAnnot[i]})
+	}
+	return &fj
+}
+
+// Check that an output format is supported
+func IsResultFormat(f string) bool {
+	return f == TSV_FORMAT || f == JSON_FORMAT || f == JSONL_FORMAT
+}
+
+// Write all the results in the requested format (tsv, json or jsonl)
+func (fa *Fannot) WriteResults(w io.Writer, format string) error {
+	bw := bufio.NewWriter(w)
+
+	switch format {
+	case TSV_FORMAT:
+		err := WriteFAResultsHeader(bw)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < fa.NQueries; i++ {
+			err = fa.Results[i].WriteFAResult(bw, fa.Queries[i].Id)
+			if err != nil {
+				return err
+			}
+		}
+	case JSON_FORMAT:
+		res := make([]*FAResultJson, fa.NQueries)
+		for i := 0; i < fa.NQueries; i++ {
+			res[i] = fa.Results[i].Json(fa.Queries[i].Id)
+		}
+		jw := json.NewEncoder(bw)
+		jw.SetIndent("", "  ")
+		err := jw.Encode(res)
+		if err != nil {
+			return err
+		}
+	case JSONL_FORMAT:
+		// One JSON object per line
+		jw := json.NewEncoder(bw)
+		for i := 0; i < fa.NQueries; i++ {
+			err := jw.Encode(fa.Results[i].Json(fa.Queries[i].Id))
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Unknown output format: %s.", format)
+	}
+
+	return bw.Flush()
+}
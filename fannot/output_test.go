@@ -0,0 +1,99 @@
+package fannot
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hdevillers/go-fannot/ips"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+func TestWriteResultsJson(t *testing.T) {
+	queries := []seq.Seq{
+		newTestSeq("q1", "", "MKVLAGT"),
+		newTestSeq("q2", "", "MKVLAGT"),
+	}
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+		newTestSeq("P2", "Protein kinase B::PKB1::::Saccharomyces cerevisiae::", "MKVLAGTMKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{
+		"q1": {{Id: "P1", Evalue: 1e-50, BitScore: 200.0}, {Id: "P2", Evalue: 1e-10, BitScore: 80.0}},
+	}}
+	a := &fakeAligner{map[string]float64{
+		"q1/P1": 95.0,
+		"q1/P2": 40.0,
+	}}
+	fa := newTestFannot(queries, entries, s, a)
+	err := runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ie := ips.NewIpsEntry("q1")
+	ie.KeyValue["IPR000719"] = "protein kinase domain"
+	ie.KeyValue["IPR008271"] = "serine/threonine-protein kinase, active site"
+	fa.Ips.Data["q1"] = ie
+	fa.AddIpsAnnot()
+
+	var out bytes.Buffer
+	err = fa.WriteResults(&out, JSONL_FORMAT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 JSON lines, found %d.", len(lines))
+	}
+
+	var r FAResultJson
+	err = json.Unmarshal([]byte(lines[0]), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Query != "q1" || r.RefID != "P1" || r.DBID != "testdb" || !strings.HasPrefix(r.Rule, "rule 1:") {
+		t.Errorf("Unexpected result for q1: %v.", r)
+	}
+	if len(r.Hits) != 2 || r.Hits[1].Id != "P2" || r.Hits[1].Similarity != 40.0 || r.Hits[1].LengthRatio != 0.5 || r.Hits[1].DB != "testdb" {
+		t.Errorf("Unexpected examined hits for q1: %v.", r.Hits)
+	}
+	if len(r.InterPro) != 2 || r.InterPro[0].Id != "IPR000719" || r.InterPro[0].Annot != "protein kinase domain" {
+		t.Errorf("Unexpected InterPro data for q1: %v.", r.InterPro)
+	}
+
+	// The JSON array holds the same objects
+	out.Reset()
+	err = fa.WriteResults(&out, JSON_FORMAT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all []FAResultJson
+	err = json.Unmarshal(out.Bytes(), &all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[1].Query != "q2" || all[1].Hits == nil || len(all[1].Hits) != 0 {
+		t.Errorf("Unexpected JSON array: %v.", all)
+	}
+}
+
+// InterPro columns of the TSV output (sorted IDs)
+func TestWriteResultsIps(t *testing.T) {
+	queries := []seq.Seq{newTestSeq("q1", "", "MKVLAGT")}
+	fa := newTestFannot(queries, nil, nil, nil)
+	ie := ips.NewIpsEntry("q1")
+	ie.KeyValue["IPR008271"] = "active site"
+	ie.KeyValue["IPR000719"] = "protein kinase domain"
+	fa.Ips.Data["q1"] = ie
+	fa.AddIpsAnnot()
+
+	var out bytes.Buffer
+	err := fa.WriteResults(&out, TSV_FORMAT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "\tIPR000719,IPR008271\tprotein kinase domain; active site\t") {
+		t.Errorf("Unexpected InterPro columns: %s.", out.String())
+	}
+}
@@ -0,0 +1,71 @@
+package fannot
+
+import (
+	"sort"
+	"strings"
+)
+
+// Indicate if the result of the query i is better than the result of the
+// query j (status, then similarity, length ratio and query ID)
+func (fa *Fannot) betterResult(i, j int) bool {
+	ri, rj := &fa.Results[i], &fa.Results[j]
+	if ri.Status != rj.Status {
+		return ri.Status > rj.Status
+	}
+	if ri.HitSim != rj.HitSim {
+		return ri.HitSim > rj.HitSim
+	}
+	if ri.HitLR != rj.HitLR {
+		return ri.HitLR > rj.HitLR
+	}
+	return fa.Queries[i].Id < fa.Queries[j].Id
+}
+
+// Find the gene names copied to several queries (the case is ignored)
+// and apply the Dup_nam policy: the name is kept on the best query and
+// the other queries are noted as its paralogs (note) and also lose the
+// name (demote). Return the number of paralogs.
+func (fa *Fannot) DedupNames() int {
+	if fa.FaPar.Dup_nam == DUP_KEEP {
+		return 0
+	}
+
+	// Group the queries by copied gene name
+	groups := make(map[string][]int)
+	names := make([]string, 0)
+	for i := 0; i < fa.NQueries; i++ {
+		r := &fa.Results[i]
+		if !r.CopyGID || !r.HasHit() || r.Name == "" || r.Name == "Null" {
+			continue
+		}
+		key := strings.ToUpper(r.Name)
+		if _, ok := groups[key]; !ok {
+			names = append(names, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	np := 0
+	for _, name := range names {
+		qis := groups[name]
+		if len(qis) < 2 {
+			continue
+		}
+		sort.Slice(qis, func(a, b int) bool {
+			return fa.betterResult(qis[a], qis[b])
+		})
+
+		best := fa.Queries[qis[0]].Id
+		for _, qi := range qis[1:] {
+			r := &fa.Results[qi]
+			r.ParalogOf = best
+			r.Note += ", paralog of " + best
+			if fa.FaPar.Dup_nam == DUP_DEMOTE {
+				r.CopyGID = false
+			}
+			np++
+		}
+	}
+
+	return np
+}
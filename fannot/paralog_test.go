@@ -0,0 +1,57 @@
+package fannot
+
+import (
+	"testing"
+
+	"github.com/hdevillers/go-seq/seq"
+)
+
+func TestParalogDedupNames(t *testing.T) {
+	queries := []seq.Seq{
+		newTestSeq("q1", "", "MKVLAGT"),
+		newTestSeq("q2", "", "MKVLAGT"),
+		newTestSeq("q3", "", "MKVLAGT"),
+	}
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+		newTestSeq("P2", "Protein kinase A::Pka1::::Candida albicans::", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{
+		"q1": {{Id: "P1"}},
+		"q2": {{Id: "P1"}},
+		"q3": {{Id: "P2"}},
+	}}
+	a := &fakeAligner{map[string]float64{
+		"q1/P1": 85.0,
+		"q2/P1": 95.0,
+		"q3/P2": 90.0,
+	}}
+
+	for _, pol := range []string{DUP_KEEP, DUP_NOTE, DUP_DEMOTE} {
+		fa := newTestFannot(queries, entries, s, a)
+		fa.FaPar.Dup_nam = pol
+		err := runTestFannot(fa)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		np := fa.DedupNames()
+		if pol == DUP_KEEP {
+			if np != 0 || fa.Results[0].ParalogOf != "" {
+				t.Errorf("Names should not be deduplicated with the policy %s.", pol)
+			}
+			continue
+		}
+
+		// q2 is the most similar query
+		if np != 2 || !fa.Results[1].CopyGID || fa.Results[1].ParalogOf != "" {
+			t.Errorf("Expected q2 to keep the name and two paralogs, found %d paralogs.", np)
+		}
+		for _, qi := range []int{0, 2} {
+			r := fa.Results[qi]
+			if r.ParalogOf != "q2" || r.CopyGID != (pol == DUP_NOTE) {
+				t.Errorf("Unexpected paralog %s with the policy %s: %s (%t).", queries[qi].Id, pol, r.ParalogOf, r.CopyGID)
+			}
+		}
+	}
+}
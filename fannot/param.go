@@ -1,9 +1,13 @@
 package fannot
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
-	"os"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hdevillers/go-fannot/expr"
 )
 
 // Default thresholds
@@ -14,6 +18,8 @@ const (
 	MIN_LRA_NORM float64 = 0.7
 	MIN_SIM_NORM float64 = 50.0
 	UNKNOWN_FUNC string  = "hypothetical protein"
+	UNKNOWN_NOTE string  = "{product}"
+	UNKNOWN_STA  int     = 0
 	PRE_SIM_HIGH string  = "highly similar to"
 	PRE_SIM_NORM string  = "similar to"
 	CPY_GEN_HIGH bool    = true
@@ -22,25 +28,297 @@ const (
 	OVR_WRT_NORM bool    = false
 	HIT_STA_HIGH int     = 2
 	HIT_STA_NORM int     = 1
+	GLOBAL_RULES string  = "global"
+)
+
+// Policies applied when the qualifying hits disagree (consensus mode)
+const (
+	CNS_NOTE  string = "note"  // Report the conflicting products in the note
+	CNS_LOWER string = "lower" // Apply the next (less stringent) rule
 )
 
-// Single rule object
+// Policies applied to gene names copied to several queries
+const (
+	DUP_KEEP   string = "keep"   // Keep all the names
+	DUP_NOTE   string = "note"   // Keep all the names, note the paralogs
+	DUP_DEMOTE string = "demote" // Keep the name on the best query only
+)
+
+// Criteria of the tie-break chain between equally similar hits (the
+// accession always ends the chain)
+const (
+	TIE_REVIEWED  string = "reviewed"     // Reviewed over unreviewed references
+	TIE_EVIDENCE  string = "evidence"     // Stronger protein existence level
+	TIE_LENGTH    string = "length_ratio" // Higher length ratio
+	TIE_GENE_NAME string = "gene_name"    // References with a gene name
+	TIE_FUNCTION  string = "function"     // References with a function comment
+	TIE_ACCESSION string = "accession"    // Lowest accession (lexicographic order)
+)
+
+// Check that a tie-break criterion is supported
+func IsTieBreak(c string) bool {
+	switch c {
+	case TIE_REVIEWED, TIE_EVIDENCE, TIE_LENGTH, TIE_GENE_NAME, TIE_FUNCTION, TIE_ACCESSION:
+		return true
+	}
+	return false
+}
+
+// Statistics of a hit evaluated by the rules (percentages for
+// alignment values)
+type HitStats struct {
+	Similarity  float64 `json:"similarity"`
+	Identity    float64 `json:"identity"`
+	LengthRatio float64 `json:"length_ratio"`
+	QueryCov    float64 `json:"query_cov"`
+	TargetCov   float64 `json:"target_cov"`
+	Evalue      float64 `json:"evalue"`
+	BitScore    float64 `json:"bitscore"`
+}
+
+// Attributes of a hit available in rule expressions
+type HitAttrs struct {
+	HitStats
+	Db       string   // Reference DB ID
+	Organism string   // Species of the reference
+	Name     string   // Gene name of the reference
+	Function string   // Function comment of the reference
+	Lineage  []string // Taxonomic lineage of the reference
+	Reviewed bool     // The reference is reviewed (Swiss-Prot)
+	Evidence int      // Protein existence level (1 to 5, 0 if unknown)
+}
+
+// Variables of the rule expressions
+var RuleEnv = expr.Env{
+	"sim":      expr.NUMBER,
+	"identity": expr.NUMBER,
+	"lra":      expr.NUMBER,
+	"qcov":     expr.NUMBER,
+	"tcov":     expr.NUMBER,
+	"evalue":   expr.NUMBER,
+	"bitscore": expr.NUMBER,
+	"evidence": expr.NUMBER,
+	"db":       expr.STRING,
+	"organism": expr.STRING,
+	"genus":    expr.STRING,
+	"name":     expr.STRING,
+	"lineage":  expr.LIST,
+	"reviewed": expr.BOOL,
+}
+
+// Return the values of the rule expression variables
+func (h *HitAttrs) Vars() expr.Vars {
+	lineage := h.Lineage
+	if lineage == nil {
+		lineage = []string{}
+	}
+	return expr.Vars{
+		"sim":      h.Similarity,
+		"identity": h.Identity,
+		"lra":      h.LengthRatio,
+		"qcov":     h.QueryCov,
+		"tcov":     h.TargetCov,
+		"evalue":   h.Evalue,
+		"bitscore": h.BitScore,
+		"evidence": float64(h.Evidence),
+		"db":       h.Db,
+		"organism": h.Organism,
+		"genus":    strings.SplitN(h.Organism, " ", 2)[0],
+		"name":     h.Name,
+		"lineage":  lineage,
+		"reviewed": h.Reviewed,
+	}
+}
+
+// Single rule object, optional thresholds are disabled when set to 0
 type Rule struct {
 	Min_sim float64 // Minimal similarity threshold
 	Min_lra float64 // Minimal length ratio threshold
+	Min_idt float64 // Minimal identity threshold (optional)
+	Min_qcv float64 // Minimal query coverage threshold (optional)
+	Min_tcv float64 // Minimal target coverage threshold (optional)
+	Max_eva float64 // Maximal e-value threshold (optional)
+	Min_bsc float64 // Minimal bitscore threshold (optional)
+	Cnd_exp string  // Additional condition (expression, optional)
 	Pre_ann string  // Annotation prefix
 	Cpy_gen bool    // Copy the gene name in the annotation
+	Cpy_exp string  // Copy the gene name if true (expression, replaces Cpy_gen)
+	Cpy_ont bool    // Transfer the GO terms, EC numbers and pathways
 	Ovr_wrt bool    // Can overwrite a previous annotation
+	Ovr_exp string  // Can overwrite if true (expression, replaces Ovr_wrt)
 	Hit_sta int     // Hit status (integer)
+
+	// Compiled expressions
+	cnd *expr.Expr
+	cpy *expr.Expr
+	ovr *expr.Expr
 }
 
-// Global parameter object
-type Param struct {
-	Unk_ann string
+// Compile the expressions of the rule
+func (r *Rule) Compile() error {
+	var err error
+	r.cnd, err = compileRuleExpr("Cnd_exp", r.Cnd_exp)
+	if err != nil {
+		return err
+	}
+	r.cpy, err = compileRuleExpr("Cpy_exp", r.Cpy_exp)
+	if err != nil {
+		return err
+	}
+	r.ovr, err = compileRuleExpr("Ovr_exp", r.Ovr_exp)
+	return err
+}
+
+func compileRuleExpr(field, src string) (*expr.Expr, error) {
+	if src == "" {
+		return nil, nil
+	}
+	e, err := expr.Compile(src, RuleEnv)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s expression (%s) %w", field, src, err)
+	}
+	return e, nil
+}
+
+// Evaluate an optional rule expression (compiled on the fly if
+// necessary), def is returned if the expression is not set
+func evalRuleExpr(field, src string, e *expr.Expr, h *HitAttrs, def bool) (bool, error) {
+	if src == "" {
+		return def, nil
+	}
+	if e == nil {
+		var err error
+		e, err = compileRuleExpr(field, src)
+		if err != nil {
+			return false, err
+		}
+	}
+	return e.Eval(h.Vars())
+}
+
+// Check that a hit satisfies the thresholds and the condition of the rule
+func (r *Rule) Accept(h *HitAttrs) (bool, error) {
+	if !r.Match(&h.HitStats) {
+		return false, nil
+	}
+	return evalRuleExpr("Cnd_exp", r.Cnd_exp, r.cnd, h, true)
+}
+
+// Indicate if the gene name of the hit can be copied
+func (r *Rule) CopyGene(h *HitAttrs) (bool, error) {
+	return evalRuleExpr("Cpy_exp", r.Cpy_exp, r.cpy, h, r.Cpy_gen)
+}
+
+// Indicate if the hit can overwrite a previous annotation
+func (r *Rule) CanOverwrite(h *HitAttrs) (bool, error) {
+	return evalRuleExpr("Ovr_exp", r.Ovr_exp, r.ovr, h, r.Ovr_wrt)
+}
+
+// Check that a hit satisfies all the conditions of the rule
+func (r Rule) Match(h *HitStats) bool {
+	if h.Similarity < r.Min_sim || h.LengthRatio < r.Min_lra {
+		return false
+	}
+	if h.Identity < r.Min_idt || h.QueryCov < r.Min_qcv || h.TargetCov < r.Min_tcv {
+		return false
+	}
+	if r.Max_eva > 0 && h.Evalue > r.Max_eva {
+		return false
+	}
+	return h.BitScore >= r.Min_bsc
+}
+
+// Describe the conditions of a rule
+func (r Rule) String() string {
+	conds := []string{
+		fmt.Sprintf("similarity >= %.02f%%", r.Min_sim),
+		fmt.Sprintf("length ratio >= %.02f", r.Min_lra),
+	}
+	if r.Min_idt > 0 {
+		conds = append(conds, fmt.Sprintf("identity >= %.02f%%", r.Min_idt))
+	}
+	if r.Min_qcv > 0 {
+		conds = append(conds, fmt.Sprintf("query coverage >= %.02f%%", r.Min_qcv))
+	}
+	if r.Min_tcv > 0 {
+		conds = append(conds, fmt.Sprintf("target coverage >= %.02f%%", r.Min_tcv))
+	}
+	if r.Max_eva > 0 {
+		conds = append(conds, fmt.Sprintf("e-value <= %g", r.Max_eva))
+	}
+	if r.Min_bsc > 0 {
+		conds = append(conds, fmt.Sprintf("bitscore >= %.01f", r.Min_bsc))
+	}
+	if r.Cnd_exp != "" {
+		conds = append(conds, "("+r.Cnd_exp+")")
+	}
+	return strings.Join(conds, " and ")
+}
+
+// Rules specific to a reference DB, missing values are taken from
+// the global parameters
+type RuleSet struct {
 	Nbh_chk int
 	Rules   []Rule
 }
 
+// Global parameter object
+type Param struct {
+	Unk_ann  string // Product of queries without annotation
+	Unk_not  string // Note template of queries without annotation
+	Unk_sta  int    // Status of queries without annotation
+	Nbh_chk  int
+	Rules    []Rule
+	Db_rules map[string]RuleSet // Rule sets keyed by reference DB ID
+	Cns_mod  bool               // Annotate from the majority product of the qualifying hits
+	Cns_cfl  string             // Policy applied to conflicting hits (note or lower)
+	Tie_brk  []string           // Tie-break chain between equally similar hits
+	Dup_nam  string             // Policy applied to duplicated gene names
+}
+
+// Return the rule set applied to a reference DB: its name (the DB ID
+// or GLOBAL_RULES), the number of hits to check and the rules
+func (p *Param) GetRuleSet(db string) (string, int, []Rule) {
+	rs, ok := p.Db_rules[db]
+	if !ok {
+		return GLOBAL_RULES, p.Nbh_chk, p.Rules
+	}
+	nbh := rs.Nbh_chk
+	if nbh == 0 {
+		nbh = p.Nbh_chk
+	}
+	rules := rs.Rules
+	if len(rules) == 0 {
+		rules = p.Rules
+	}
+	return db, nbh, rules
+}
+
+// Compile the expressions of all the rules (global and DB specific)
+func (p *Param) Compile() error {
+	for i := range p.Rules {
+		err := p.Rules[i].Compile()
+		if err != nil {
+			return fmt.Errorf("Rule %d: %w", i+1, err)
+		}
+	}
+	for db, rs := range p.Db_rules {
+		for i := range rs.Rules {
+			err := rs.Rules[i].Compile()
+			if err != nil {
+				return fmt.Errorf("Rule %d of the DB %s: %w", i+1, db, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Return the note of a query without annotation, {product} and
+// {query} are replaced by the product and the query ID
+func (p *Param) UnknownNote(qid string) string {
+	return strings.NewReplacer("{product}", p.Unk_ann, "{query}", qid).Replace(p.Unk_not)
+}
+
 // Create a new parameter object with default values
 func NewParam() *Param {
 	var p Param
@@ -48,10 +326,29 @@ func NewParam() *Param {
 	// Main values
 	p.Nbh_chk = N_BEST_HITS
 	p.Unk_ann = UNKNOWN_FUNC
+	p.Unk_not = UNKNOWN_NOTE
+	p.Unk_sta = UNKNOWN_STA
+	p.Cns_cfl = CNS_NOTE
+	p.Dup_nam = DUP_KEEP
+	p.Tie_brk = []string{TIE_REVIEWED, TIE_EVIDENCE, TIE_LENGTH, TIE_GENE_NAME, TIE_FUNCTION, TIE_ACCESSION}
 
 	// Prepare rules
-	rule_high := Rule{MIN_SIM_HIGH, MIN_LRA_HIGH, PRE_SIM_HIGH, CPY_GEN_HIGH, OVR_WRT_HIGH, HIT_STA_HIGH}
-	rule_norm := Rule{MIN_SIM_NORM, MIN_LRA_NORM, PRE_SIM_NORM, CPY_GEN_NORM, OVR_WRT_NORM, HIT_STA_NORM}
+	rule_high := Rule{
+		Min_sim: MIN_SIM_HIGH,
+		Min_lra: MIN_LRA_HIGH,
+		Pre_ann: PRE_SIM_HIGH,
+		Cpy_gen: CPY_GEN_HIGH,
+		Ovr_wrt: OVR_WRT_HIGH,
+		Hit_sta: HIT_STA_HIGH,
+	}
+	rule_norm := Rule{
+		Min_sim: MIN_SIM_NORM,
+		Min_lra: MIN_LRA_NORM,
+		Pre_ann: PRE_SIM_NORM,
+		Cpy_gen: CPY_GEN_NORM,
+		Ovr_wrt: OVR_WRT_NORM,
+		Hit_sta: HIT_STA_NORM,
+	}
 	p.Rules = make([]Rule, 2)
 	p.Rules[0] = rule_high
 	p.Rules[1] = rule_norm
@@ -60,29 +357,40 @@ func NewParam() *Param {
 	return &p
 }
 
-// Create a new parameter object from a JSON
-func NewParamFromJson(file string) *Param {
-	var p Param
+// Create a new parameter object from a JSON, missing values are set
+// to their default (except rules) and unknown fields are rejected
+func NewParamFromJson(file string) (*Param, error) {
+	p := NewParam()
+	p.Rules = nil
 
-	// Open the file
-	f, err := os.Open(file)
+	// Read the file
+	data, err := ioutil.ReadFile(file)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	defer f.Close()
 
-	// Create the reader
-	fr := bufio.NewReader(f)
+	// Check field names (case sensitive)
+	err = checkParamKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode the rules file %s: %w", file, err)
+	}
 
 	// Create the json decoder
-	jr := json.NewDecoder(fr)
+	jr := json.NewDecoder(bytes.NewReader(data))
+	jr.DisallowUnknownFields()
 
 	// Decode the entry
-	err = jr.Decode(&p)
+	err = jr.Decode(p)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("Failed to decode the rules file %s: %w", file, err)
+	}
+
+	// Validate rule expressions
+	err = p.Compile()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid rules file %s: %w", file, err)
 	}
 
 	// Return
-	return &p
+	return p, nil
 }
@@ -1,7 +1,11 @@
 package fannot
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seq"
 )
 
 // Test the default parameter settings
@@ -43,10 +47,299 @@ func TestParamDefault(t *testing.T) {
 
 // Test to read the three rules JSON (in example)
 func TestParamThreeRules(t *testing.T) {
-	p := NewParamFromJson("../examples/three_levels.json")
+	p, err := NewParamFromJson("../examples/three_levels.json")
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Check the number of rules
 	if len(p.Rules) != 3 {
 		t.Errorf("Expecting 3 default rules, found %d", len(p.Rules))
 	}
 }
+
+// Test custom settings of queries without annotation
+func TestParamUnknown(t *testing.T) {
+	p, err := NewParamFromJson("../examples/uncharacterized.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Unk_ann != "uncharacterized protein" || p.Unk_sta != -1 {
+		t.Errorf("Unexpected unknown product or status: %s, %d.", p.Unk_ann, p.Unk_sta)
+	}
+	note := p.UnknownNote("g1")
+	if note != "uncharacterized protein g1, no significant similarity found" {
+		t.Errorf("Unexpected unknown note: %s.", note)
+	}
+
+	// Missing values are set to their default
+	if p.Nbh_chk != N_BEST_HITS {
+		t.Errorf("Default number of hits should be %d, found %d", N_BEST_HITS, p.Nbh_chk)
+	}
+	if NewParam().UnknownNote("g1") != UNKNOWN_FUNC {
+		t.Errorf("Default unknown note should be %s.", UNKNOWN_FUNC)
+	}
+}
+
+// Test the optional thresholds of the rules
+func TestRuleMatch(t *testing.T) {
+	p, err := NewParamFromJson("../examples/extended_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	high, norm := p.Rules[0], p.Rules[1]
+
+	h := HitStats{
+		Similarity:  90.0,
+		Identity:    70.0,
+		LengthRatio: 0.95,
+		QueryCov:    98.0,
+		TargetCov:   95.0,
+		Evalue:      1e-50,
+		BitScore:    300.0,
+	}
+	if !high.Match(&h) || !norm.Match(&h) {
+		t.Error("The hit should satisfy both rules.")
+	}
+
+	// Each optional threshold is evaluated
+	for _, f := range []func(h *HitStats){
+		func(h *HitStats) { h.Identity = 50.0 },
+		func(h *HitStats) { h.QueryCov = 80.0 },
+		func(h *HitStats) { h.TargetCov = 80.0 },
+		func(h *HitStats) { h.Evalue = 1e-20 },
+	} {
+		hc := h
+		f(&hc)
+		if high.Match(&hc) {
+			t.Errorf("The hit %v should not satisfy the first rule.", hc)
+		}
+		if !norm.Match(&hc) {
+			t.Errorf("The hit %v should satisfy the second rule.", hc)
+		}
+	}
+	hc := h
+	hc.BitScore = 40.0
+	if norm.Match(&hc) {
+		t.Error("The bitscore threshold of the second rule is not evaluated.")
+	}
+
+	// Disabled thresholds (default rules)
+	hc = HitStats{Similarity: 90.0, LengthRatio: 0.9, Evalue: 10.0}
+	if !NewParam().Rules[0].Match(&hc) {
+		t.Error("Optional thresholds should be disabled in default rules.")
+	}
+}
+
+// Test the rule sets specific to reference DBs
+func TestParamDbRules(t *testing.T) {
+	p, err := NewParamFromJson("../examples/db_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, nbh, rules := p.GetRuleSet("trembl")
+	if name != "trembl" || nbh != 10 || len(rules) != 2 || rules[0].Min_sim != 90.0 {
+		t.Errorf("Unexpected rule set for trembl: %s, %d, %v.", name, nbh, rules)
+	}
+
+	// Missing rules are taken from the global parameters
+	name, nbh, rules = p.GetRuleSet("swissprot_fungi")
+	if name != "swissprot_fungi" || nbh != 5 || len(rules) != 2 || rules[0].Min_sim != 80.0 {
+		t.Errorf("Unexpected rule set for swissprot_fungi: %s, %d, %v.", name, nbh, rules)
+	}
+
+	// Unknown DB: global rule set
+	name, nbh, _ = p.GetRuleSet("other")
+	if name != GLOBAL_RULES || nbh != 3 {
+		t.Errorf("Expected the global rule set, found %s (%d hits).", name, nbh)
+	}
+}
+
+// Test the rule expressions
+func TestParamExpressions(t *testing.T) {
+	p, err := NewParamFromJson("../examples/expression_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	high, norm := p.Rules[0], p.Rules[1]
+
+	h := HitAttrs{
+		HitStats: HitStats{Similarity: 92.0, LengthRatio: 0.9},
+		Organism: "Saccharomyces cerevisiae",
+		Lineage:  []string{"Eukaryota", "Fungi", "Saccharomyces"},
+		Evidence: 3,
+		Reviewed: true,
+	}
+	ok, err := high.Accept(&h)
+	if err != nil || !ok {
+		t.Errorf("The hit should satisfy the first rule (%v).", err)
+	}
+	cpy, err := high.CopyGene(&h)
+	if err != nil || !cpy {
+		t.Errorf("The gene name should be copied (%v).", err)
+	}
+	ovr, _ := high.CanOverwrite(&h)
+	if !ovr {
+		t.Error("Ovr_wrt should apply without Ovr_exp.")
+	}
+
+	// Other genus and unreviewed low evidence
+	h.Organism = "Candida albicans"
+	cpy, _ = high.CopyGene(&h)
+	if cpy {
+		t.Error("The gene name should not be copied from another genus.")
+	}
+	h.Reviewed = false
+	ok, _ = high.Accept(&h)
+	if ok {
+		t.Error("The condition of the first rule is not evaluated.")
+	}
+	ok, _ = norm.Accept(&h)
+	if !ok {
+		t.Error("The hit should satisfy the second rule.")
+	}
+}
+
+// Test the expression errors detected when loading rules
+func TestParamExpressionErrors(t *testing.T) {
+	r := Rule{Cnd_exp: "sim >= 90 and genre == \"Saccharomyces\""}
+	err := r.Compile()
+	if err == nil || !strings.Contains(err.Error(), "position 15: unknown variable genre") {
+		t.Errorf("Expected an unknown variable error at position 15, found %v.", err)
+	}
+
+	r = Rule{Cpy_exp: "sim >= 90 and"}
+	err = r.Compile()
+	if err == nil || !strings.Contains(err.Error(), "Cpy_exp") {
+		t.Errorf("Expected an error on Cpy_exp, found %v.", err)
+	}
+}
+
+func TestFindFunctionUnknownParam(t *testing.T) {
+	queries := newTestQueries(2)
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P1"}}}}
+	a := &fakeAligner{map[string]float64{"q1/P1": 90.0}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	p, err := NewParamFromJson("../examples/uncharacterized.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fa.SetParam(p)
+	err = runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fa.Results[0].Product != "protein kinase A" || !fa.Results[0].HasHit() {
+		t.Errorf("Query q1 should be annotated, found %s.", fa.Results[0].Product)
+	}
+	r := fa.Results[1]
+	if r.Product != "uncharacterized protein" || r.Status != -1 || r.HasHit() {
+		t.Errorf("Unexpected result of q2: %s (status %d).", r.Product, r.Status)
+	}
+	if r.Note != "uncharacterized protein q2, no significant similarity found" {
+		t.Errorf("Unexpected note of q2: %s.", r.Note)
+	}
+}
+
+func TestFindFunctionExtendedRules(t *testing.T) {
+	queries := newTestQueries(1)
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P1", Evalue: 1e-10, BitScore: 120.0}}}}
+	a := &fakeAligner{map[string]float64{"q1/P1": 95.0}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	p, err := NewParamFromJson("../examples/extended_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fa.SetParam(p)
+	err = runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The e-value is too high for the first rule
+	if fa.Results[0].Status != 1 || !strings.HasPrefix(fa.Results[0].Rule, "rule 2:") {
+		t.Errorf("Expected the second rule to validate q1, found %s.", fa.Results[0].Rule)
+	}
+}
+
+func TestFindFunctionDbRules(t *testing.T) {
+	queries := newTestQueries(1)
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P1"}}}}
+	a := &fakeAligner{map[string]float64{"q1/P1": 85.0}}
+
+	p, err := NewParamFromJson("../examples/db_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, db := range []string{"testdb", "trembl"} {
+		fa := newTestFannot(queries, entries, s, a)
+		fa.DBs[0].Id = db
+		fa.SetParam(p)
+		err = runTestFannot(fa)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 85% is highly similar with the global rules only
+		r := fa.Results[0]
+		if db == "testdb" && (r.Status != 2 || r.RuleSet != GLOBAL_RULES) {
+			t.Errorf("Expected status 2 with the global rules, found %d (%s).", r.Status, r.RuleSet)
+		}
+		if db == "trembl" && (r.Status != 1 || r.RuleSet != "trembl") {
+			t.Errorf("Expected status 1 with the trembl rules, found %d (%s).", r.Status, r.RuleSet)
+		}
+	}
+}
+
+func TestFindFunctionExpressions(t *testing.T) {
+	queries := newTestQueries(2)
+	entries := []seq.Seq{
+		newTestSeq("P1", "", "MKVLAGT"),
+		newTestSeq("P2", "", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{
+		"q1": {{Id: "P1"}},
+		"q2": {{Id: "P2"}},
+	}}
+	a := &fakeAligner{map[string]float64{
+		"q1/P1": 70.0,
+		"q2/P2": 70.0,
+	}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	setTestMeta(t, fa, []refdb.Meta{
+		{Access: "P1", Desc: "Protein kinase A", Name: "PKA1", Organism: "Saccharomyces cerevisiae (strain S288c).", Lineage: []string{"Eukaryota", "Fungi", "Saccharomyces"}, Evidence: 1},
+		{Access: "P2", Desc: "Protein kinase B", Name: "PKB1", Organism: "Homo sapiens", Lineage: []string{"Eukaryota", "Metazoa", "Homo"}, Evidence: 1},
+	})
+	p, err := NewParamFromJson("../examples/expression_rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fa.SetParam(p)
+	err = runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only fungal references satisfy the second rule
+	if fa.Results[0].Status != 1 || fa.Results[0].GeneID != "P1" {
+		t.Errorf("Query q1 should be annotated from P1 with status 1, found %s (%d).", fa.Results[0].GeneID, fa.Results[0].Status)
+	}
+	if fa.Results[1].HasHit() {
+		t.Errorf("Query q2 should not be annotated, found %s.", fa.Results[1].GeneID)
+	}
+}
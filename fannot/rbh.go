@@ -0,0 +1,100 @@
+package fannot
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seqio/fasta"
+)
+
+// ID of the search DB built from the queries
+const QUERY_DB string = "queries"
+
+// Outcomes of the reciprocal best hit check
+const (
+	RBH_CONFIRMED string = "confirmed" // The query is the best hit of the reference
+	RBH_REJECTED  string = "rejected"  // Another query is the best hit of the reference
+)
+
+// Build the search DB of the queries in the directory dir (backend
+// blast, diamond or mmseqs), used to check reciprocal best hits
+func (fa *Fannot) MakeQueryDB(dir, backend string) error {
+	rdb := refdb.Refdb{
+		Id:       QUERY_DB,
+		Root:     dir,
+		Fasta:    dir + "/" + refdb.FASTA_PATH,
+		Backends: []string{backend},
+		Nprot:    fa.NQueries,
+	}
+
+	// Write the queries
+	f, err := os.Create(rdb.Fasta)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fw := fasta.NewWriter(bufio.NewWriter(f))
+	for _, q := range fa.Queries {
+		err = fw.Write(q)
+		if err != nil {
+			return err
+		}
+	}
+	err = fw.Flush()
+	if err != nil {
+		return err
+	}
+
+	switch backend {
+	case refdb.BLAST_BACKEND:
+		err = rdb.MakeBlastDB()
+	case refdb.DIAMOND_BACKEND:
+		err = rdb.MakeDiamondDB()
+	case refdb.MMSEQS_BACKEND:
+		err = rdb.MakeMmseqsDB()
+	default:
+		err = fmt.Errorf("Unknown search backend: %s.", backend)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to build the search DB of the queries: %w", err)
+	}
+
+	fa.QueryDB = &rdb
+	return nil
+}
+
+// Search the reference hid back against the queries and indicate if the
+// query qi is its best hit (ties on the bit score are accepted). The best
+// hit of the reference is also returned.
+func (fa *Fannot) reciprocal(qi int, hid string) (bool, string, error) {
+	ref, err := fa.dbEntry(hid)
+	if err != nil {
+		return false, "", err
+	}
+	cands, err := fa.Searcher.Search(ref, fa.QueryDB)
+	if err != nil {
+		return false, "", fmt.Errorf("Failed to search the reference %s in the queries: %w", hid, err)
+	}
+	ok, best := reciprocalBest(cands, fa.Queries[qi].Id)
+	return ok, best, nil
+}
+
+// Indicate if the query qid is the best hit among the candidates of a
+// reference search (ties on the bit score are accepted), the best hit is
+// also returned ("" without candidate)
+func reciprocalBest(cands []Candidate, qid string) (bool, string) {
+	if len(cands) == 0 {
+		return false, ""
+	}
+	for _, c := range cands {
+		if c.BitScore < cands[0].BitScore {
+			break
+		}
+		if c.Id == qid {
+			return true, c.Id
+		}
+	}
+	return false, cands[0].Id
+}
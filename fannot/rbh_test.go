@@ -0,0 +1,111 @@
+package fannot
+
+import (
+	"testing"
+
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+func TestReciprocalBest(t *testing.T) {
+	cands := []Candidate{
+		{Id: "q2", BitScore: 200.0},
+		{Id: "q3", BitScore: 200.0},
+		{Id: "q1", BitScore: 150.0},
+	}
+	if ok, best := reciprocalBest(cands, "q3"); !ok || best != "q3" {
+		t.Errorf("Expected q3 to be accepted (tie on the bit score), found %t (%s).", ok, best)
+	}
+	if ok, best := reciprocalBest(cands, "q1"); ok || best != "q2" {
+		t.Errorf("Expected q1 to be rejected in favor of q2, found %t (%s).", ok, best)
+	}
+	if ok, best := reciprocalBest(nil, "q1"); ok || best != "" {
+		t.Errorf("Expected a rejection without candidate, found %t (%s).", ok, best)
+	}
+}
+
+func TestFindFunctionReciprocal(t *testing.T) {
+	queries := []seq.Seq{
+		newTestSeq("q1", "", "MKVLAGT"),
+		newTestSeq("q2", "", "MKVLAGT"),
+	}
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+	}
+	// The reference P1 is searched back against the queries
+	s := &fakeSearcher{map[string][]Candidate{
+		"q1": {{Id: "P1"}},
+		"q2": {{Id: "P1"}},
+		"P1": {{Id: "q2", BitScore: 200.0}, {Id: "q1", BitScore: 150.0}},
+	}}
+	a := &fakeAligner{map[string]float64{
+		"q1/P1": 90.0,
+		"q2/P1": 95.0,
+	}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	fa.QueryDB = &refdb.Refdb{Id: QUERY_DB}
+	err := runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both queries are annotated, only q2 receives the gene name
+	r := fa.Results[0]
+	if !r.HasHit() || r.CopyGID || r.Rbh != RBH_REJECTED || r.RbhHit != "q2" {
+		t.Errorf("Expected a rejected gene name copy for q1, found %t (%s %s).", r.CopyGID, r.Rbh, r.RbhHit)
+	}
+	r = fa.Results[1]
+	if !r.CopyGID || r.Rbh != RBH_CONFIRMED {
+		t.Errorf("Expected a confirmed gene name copy for q2, found %t (%s).", r.CopyGID, r.Rbh)
+	}
+}
+
+// Searcher counting the searches of each sequence
+type countSearcher struct {
+	Searcher
+	calls map[string]int
+}
+
+func (cs *countSearcher) Search(q seq.Seq, db *refdb.Refdb) ([]Candidate, error) {
+	cs.calls[q.Id]++
+	return cs.Searcher.Search(q, db)
+}
+
+func TestFindFunctionReciprocalSkipped(t *testing.T) {
+	queries := []seq.Seq{
+		newTestSeq("q1", "", "MKVLAGT"),
+		newTestSeq("q2", "", "MKVLAGT"),
+	}
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+		newTestSeq("P2", "Protein kinase B::PKB1::::Saccharomyces cerevisiae::", "MKVLAGT"),
+	}
+	s := &countSearcher{&fakeSearcher{map[string][]Candidate{
+		"q1": {{Id: "P1"}},
+		"q2": {{Id: "P2"}},
+		"P1": {{Id: "q1", BitScore: 200.0}},
+		"P2": {{Id: "q2", BitScore: 200.0}},
+	}}, make(map[string]int)}
+	a := &fakeAligner{map[string]float64{
+		"q1/P1": 95.0,
+		"q2/P2": 95.0,
+	}}
+
+	// q1 is already annotated by a previous DB that cannot be overwritten
+	fa := newTestFannot(queries, entries, s, a)
+	fa.QueryDB = &refdb.Refdb{Id: QUERY_DB}
+	fa.Finished[0] = true
+	fa.Results[0] = FAResult{Product: "previous product", Status: 2}
+	err := runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.calls["P1"] != 0 || fa.Results[0].Product != "previous product" {
+		t.Errorf("Expected no reverse search for the annotated q1, found %d (%s).", s.calls["P1"], fa.Results[0].Product)
+	}
+	if s.calls["P2"] != 1 || fa.Results[1].Rbh != RBH_CONFIRMED {
+		t.Errorf("Expected one reverse search for q2, found %d (%s).", s.calls["P2"], fa.Results[1].Rbh)
+	}
+}
@@ -0,0 +1,110 @@
+package fannot
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/hdevillers/go-blast"
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+// Candidate hit returned by a homology search
+type Candidate struct {
+	Id       string
+	Evalue   float64
+	BitScore float64
+}
+
+// Homology search tool: return the candidate hits of a query
+// in a reference DB, ranked from the best to the worst
+type Searcher interface {
+	Search(q seq.Seq, db *refdb.Refdb) ([]Candidate, error)
+}
+
+// Homology search tool able to process several queries at once,
+// results are grouped by query ID
+type BatchSearcher interface {
+	Searcher
+	SearchBatch(qs []seq.Seq, db *refdb.Refdb) (map[string][]Candidate, error)
+}
+
+// Default searcher based on BLASTP
+type BlastSearcher struct {
+	Par *blast.Param
+}
+
+func NewBlastSearcher() *BlastSearcher {
+	return &BlastSearcher{blast.NewParam()}
+}
+
+func (bs *BlastSearcher) Search(q seq.Seq, db *refdb.Refdb) ([]Candidate, error) {
+	if !db.HasBackend(refdb.BLAST_BACKEND) {
+		return nil, fmt.Errorf("No BLAST DB available for the reference DB %s.", db.Id)
+	}
+
+	// Init. the search tool
+	blt := blast.NewBlast()
+	blt.Par = bs.Par
+	blt.Db = db.Blastdb
+
+	// Add the query and run blast
+	blt.AddQuery(q)
+	err := blt.Search()
+	if err != nil {
+		return nil, err
+	}
+
+	return blastCandidates(&blt.Rst.Iterations[0]), nil
+}
+
+// Convert the hits of a BLAST iteration into candidates
+func blastCandidates(it *blast.Iteration) []Candidate {
+	cand := make([]Candidate, len(it.Hits))
+	for i, hit := range it.Hits {
+		cand[i].Id = hit.GetHitId()
+		if len(hit.HitHsps) > 0 {
+			cand[i].Evalue = hit.HitHsps[0].Evalue
+			cand[i].BitScore = hit.HitHsps[0].BitScore
+		}
+	}
+	return cand
+}
+
+// BLASTP searcher running all queries in a single process
+// (multi-FASTA query file and tabular output)
+type BatchBlastSearcher struct {
+	BlastSearcher
+}
+
+func NewBatchBlastSearcher() *BatchBlastSearcher {
+	return &BatchBlastSearcher{*NewBlastSearcher()}
+}
+
+func (bs *BatchBlastSearcher) SearchBatch(qs []seq.Seq, db *refdb.Refdb) (map[string][]Candidate, error) {
+	if !db.HasBackend(refdb.BLAST_BACKEND) {
+		return nil, fmt.Errorf("No BLAST DB available for the reference DB %s.", db.Id)
+	}
+
+	// Write the queries in a temporary FASTA file
+	qf, err := writeTempFasta(qs)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(qf)
+
+	// Tabular output (subject title as sequence IDs are not parsed in the DB)
+	par := *bs.Par
+	par.SetOutfmt("6 qseqid stitle evalue bitscore")
+	par.SetOutput("stdout")
+	cmd := par.GetCmd(db.Blastdb)
+	cmd.Args = append(cmd.Args, "-query", qf)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTabularHits(bytes.NewReader(out))
+}
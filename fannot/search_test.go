@@ -0,0 +1,93 @@
+package fannot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hdevillers/go-seq/seq"
+)
+
+func TestFindFunctionFakeTools(t *testing.T) {
+	queries := []seq.Seq{
+		newTestSeq("q1", "", "MKVLAGT"),
+		newTestSeq("q2", "", "MKVLAGT"),
+		newTestSeq("q3", "", "MKVLAGT"),
+	}
+	entries := []seq.Seq{
+		newTestSeq("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae (strain S288c)::", "MKVLAGT"),
+		newTestSeq("P2", "Protein kinase B::PKB1::YAL001C::Saccharomyces cerevisiae::phosphorylates substrates", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{
+		"q1": {{Id: "P1"}, {Id: "P2"}},
+		"q2": {{Id: "P1"}},
+	}}
+	a := &fakeAligner{map[string]float64{
+		"q1/P1": 60.0,
+		"q1/P2": 90.0,
+		"q2/P1": 10.0,
+	}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	err := runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// q1: the best hit (P2) is highly similar
+	if !fa.Finished[0] {
+		t.Fatal("Query q1 should be annotated.")
+	}
+	if fa.Results[0].GeneID != "P2" || fa.Results[0].HitNum != 2 {
+		t.Errorf("Query q1 should be annotated from the second hit P2, found %s (hit %d).", fa.Results[0].GeneID, fa.Results[0].HitNum)
+	}
+	if fa.Results[0].Status != HIT_STA_HIGH {
+		t.Errorf("Query q1 should have status %d, found %d.", HIT_STA_HIGH, fa.Results[0].Status)
+	}
+	if fa.Results[0].Product != "protein kinase B" {
+		t.Errorf("Unexpected product for q1: %s.", fa.Results[0].Product)
+	}
+
+	// q2: the hit is too dissimilar, q3: no hit
+	for _, qi := range []int{1, 2} {
+		if fa.Finished[qi] {
+			t.Errorf("Query %s should not be annotated.", queries[qi].Id)
+		}
+		if fa.Results[qi].Product != UNKNOWN_FUNC {
+			t.Errorf("Query %s should have the default product, found %s.", queries[qi].Id, fa.Results[qi].Product)
+		}
+	}
+}
+
+func TestParseTabularHits(t *testing.T) {
+	out := "q1\tP1\t1e-50\t200.5\nq1\tP1\t1e-3\t30.0\nq1\tP2\t1e-20\t90.0\nq2\tP3\t0.001\t40.2\nq3\tP4 Protein kinase::PKA1\t1e-10\t60.0\n"
+	res, err := parseTabularHits(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res["q1"]) != 2 || res["q1"][0].Id != "P1" || res["q1"][1].Id != "P2" {
+		t.Errorf("Expected hits P1 and P2 (in this order) for q1, found %v.", res["q1"])
+	}
+	if res["q1"][0].BitScore != 200.5 {
+		t.Errorf("Expected the bit score of the first HSP (200.5), found %.01f.", res["q1"][0].BitScore)
+	}
+	if len(res["q2"]) != 1 || res["q2"][0].Evalue != 0.001 {
+		t.Errorf("Expected a single hit with e-value 0.001 for q2, found %v.", res["q2"])
+	}
+	if len(res["q3"]) != 1 || res["q3"][0].Id != "P4" {
+		t.Errorf("Expected a single hit P4 (from the subject title) for q3, found %v.", res["q3"])
+	}
+}
+
+func TestFindFunctionMissingHit(t *testing.T) {
+	queries := []seq.Seq{newTestSeq("q1", "", "MKVLAGT")}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P404"}}}}
+	a := &fakeAligner{map[string]float64{}}
+
+	fa := newTestFannot(queries, nil, s, a)
+	err := runTestFannot(fa)
+	if !errors.Is(err, ErrHitMissingFromFasta) {
+		t.Errorf("Expected ErrHitMissingFromFasta, found %v.", err)
+	}
+}
@@ -0,0 +1,95 @@
+package fannot
+
+import (
+	"strings"
+)
+
+// Compare two references with a boolean criterion (true is preferred)
+func compareBool(a, b bool) int {
+	if a == b {
+		return 0
+	}
+	if a {
+		return -1
+	}
+	return 1
+}
+
+// Compare two protein existence levels (1 is the strongest, 0 is unknown)
+func compareEvidence(a, b int) int {
+	if a == b {
+		return 0
+	}
+	if b == 0 || (a != 0 && a < b) {
+		return -1
+	}
+	return 1
+}
+
+// Compare two equally similar hits with the tie-break chain: return a
+// negative value if the hit a is preferred, a positive value if the hit
+// b is preferred, and the criterion that decided. The accession always
+// ends the chain so that the choice does not depend on the hit order.
+func compareHits(chain []string, a, b *HitAttrs, ida, idb string) (int, string) {
+	for _, c := range chain {
+		cmp := 0
+		switch c {
+		case TIE_REVIEWED:
+			cmp = compareBool(a.Reviewed, b.Reviewed)
+		case TIE_EVIDENCE:
+			cmp = compareEvidence(a.Evidence, b.Evidence)
+		case TIE_LENGTH:
+			if a.LengthRatio > b.LengthRatio {
+				cmp = -1
+			} else if a.LengthRatio < b.LengthRatio {
+				cmp = 1
+			}
+		case TIE_GENE_NAME:
+			cmp = compareBool(a.Name != "", b.Name != "")
+		case TIE_FUNCTION:
+			cmp = compareBool(a.Function != "", b.Function != "")
+		case TIE_ACCESSION:
+			cmp = strings.Compare(ida, idb)
+		}
+		if cmp != 0 {
+			return cmp, c
+		}
+	}
+	if cmp := strings.Compare(ida, idb); cmp != 0 {
+		return cmp, TIE_ACCESSION
+	}
+	return 0, ""
+}
+
+// Indicate if the hit i is preferred to the hit j: the most similar hit
+// is preferred, then the tie-break chain decides (its criterion is
+// returned)
+func preferHit(chain []string, hits []HitInfo, attrs []HitAttrs, i, j int) (bool, string) {
+	if hits[i].Similarity != hits[j].Similarity {
+		return hits[i].Similarity > hits[j].Similarity, ""
+	}
+	cmp, c := compareHits(chain, &attrs[i], &attrs[j], hits[i].Id, hits[j].Id)
+	return cmp < 0, c
+}
+
+// Return the criterion that decided the selection of the hit best among
+// the candidates: the comparison with the runner-up of the same
+// similarity ("" if no other candidate is equally similar)
+func decidingTie(chain []string, hits []HitInfo, attrs []HitAttrs, best int, cands []int) string {
+	runner := -1
+	for _, i := range cands {
+		if i == best || hits[i].Similarity != hits[best].Similarity {
+			continue
+		}
+		if runner < 0 {
+			runner = i
+		} else if ok, _ := preferHit(chain, hits, attrs, i, runner); ok {
+			runner = i
+		}
+	}
+	if runner < 0 {
+		return ""
+	}
+	_, c := compareHits(chain, &attrs[best], &attrs[runner], hits[best].Id, hits[runner].Id)
+	return c
+}
@@ -0,0 +1,125 @@
+package fannot
+
+import (
+	"testing"
+
+	"github.com/hdevillers/go-fannot/refdb"
+	"github.com/hdevillers/go-seq/seq"
+)
+
+func TestCompareHits(t *testing.T) {
+	chain := NewParam().Tie_brk
+	a := HitAttrs{Reviewed: true, Evidence: 3}
+	b := HitAttrs{Reviewed: false, Evidence: 1}
+	if cmp, c := compareHits(chain, &a, &b, "P2", "P1"); cmp >= 0 || c != TIE_REVIEWED {
+		t.Errorf("Expected the reviewed hit to be preferred, found %d (%s).", cmp, c)
+	}
+
+	// Unknown evidence levels come last
+	a.Reviewed, a.Evidence, b.Evidence = false, 0, 4
+	if cmp, c := compareHits(chain, &a, &b, "P1", "P2"); cmp <= 0 || c != TIE_EVIDENCE {
+		t.Errorf("Expected the known evidence level to be preferred, found %d (%s).", cmp, c)
+	}
+
+	// The accession ends the chain even if not listed
+	b.Evidence = 0
+	if cmp, c := compareHits([]string{TIE_REVIEWED}, &a, &b, "P1", "P2"); cmp >= 0 || c != TIE_ACCESSION {
+		t.Errorf("Expected the lowest accession to be preferred, found %d (%s).", cmp, c)
+	}
+	if cmp, c := compareHits(chain, &a, &a, "P1", "P1"); cmp != 0 || c != "" {
+		t.Errorf("Expected no preference between identical hits, found %d (%s).", cmp, c)
+	}
+}
+
+// The criterion is given by the runner-up, whatever the comparison order
+func TestDecidingTie(t *testing.T) {
+	chain := NewParam().Tie_brk
+	hits := []HitInfo{
+		{Id: "P1", HitStats: HitStats{Similarity: 90.0}},
+		{Id: "P2", HitStats: HitStats{Similarity: 90.0}},
+		{Id: "P3", HitStats: HitStats{Similarity: 90.0}},
+		{Id: "P4", HitStats: HitStats{Similarity: 95.0}},
+	}
+	attrs := []HitAttrs{
+		{Reviewed: true, Evidence: 1},
+		{Reviewed: false, Evidence: 1},
+		{Reviewed: true, Evidence: 2},
+		{},
+	}
+	if c := decidingTie(chain, hits, attrs, 0, []int{0, 1, 2}); c != TIE_EVIDENCE {
+		t.Errorf("Expected the evidence to decide against P3, found %s.", c)
+	}
+	if c := decidingTie(chain, hits, attrs, 0, []int{2, 1, 0}); c != TIE_EVIDENCE {
+		t.Errorf("Expected the evidence to decide against P3 (reversed order), found %s.", c)
+	}
+	if c := decidingTie(chain, hits, attrs, 3, []int{0, 1, 2, 3}); c != "" {
+		t.Errorf("Expected no tie for the most similar hit, found %s.", c)
+	}
+}
+
+func TestFindFunctionTieBreak(t *testing.T) {
+	queries := []seq.Seq{
+		newTestSeq("q1", "", "MKVLAGT"),
+		newTestSeq("q2", "", "MKVLAGT"),
+	}
+	entries := []seq.Seq{
+		newTestSeq("P1", "", "MKVLAGT"),
+		newTestSeq("P2", "", "MKVLAGT"),
+		newTestSeq("P3", "", "MKVLAGT"),
+		newTestSeq("P4", "", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{
+		"q1": {{Id: "P2"}, {Id: "P1"}},
+		"q2": {{Id: "P4"}, {Id: "P3"}},
+	}}
+	a := &fakeAligner{map[string]float64{
+		"q1/P1": 90.0,
+		"q1/P2": 90.0,
+		"q2/P3": 90.0,
+		"q2/P4": 90.0,
+	}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	setTestMeta(t, fa, []refdb.Meta{
+		{Access: "P1", Desc: "Protein kinase A", Name: "PKA1", Organism: "Saccharomyces cerevisiae", Evidence: 1},
+		{Access: "P2", Desc: "Protein kinase B", Name: "PKB1", Organism: "Saccharomyces cerevisiae", Evidence: 3},
+		{Access: "P3", Desc: "Glucokinase", Name: "GLK1", Organism: "Saccharomyces cerevisiae"},
+		{Access: "P4", Desc: "Hexokinase", Name: "HXK1", Organism: "Saccharomyces cerevisiae"},
+	})
+	err := runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The hit order does not matter
+	if r := fa.Results[0]; r.GeneID != "P1" || r.TieBreak != TIE_EVIDENCE {
+		t.Errorf("Expected the annotation of q1 from P1 (evidence), found %s (%s).", r.GeneID, r.TieBreak)
+	}
+	if r := fa.Results[1]; r.GeneID != "P3" || r.TieBreak != TIE_ACCESSION {
+		t.Errorf("Expected the annotation of q2 from P3 (accession), found %s (%s).", r.GeneID, r.TieBreak)
+	}
+}
+
+// The reviewed status is read from each entry, not from the DB
+func TestFindFunctionTieBreakReviewed(t *testing.T) {
+	queries := []seq.Seq{newTestSeq("q1", "", "MKVLAGT")}
+	entries := []seq.Seq{
+		newTestSeq("P1", "", "MKVLAGT"),
+		newTestSeq("P2", "", "MKVLAGT"),
+	}
+	s := &fakeSearcher{map[string][]Candidate{"q1": {{Id: "P1"}, {Id: "P2"}}}}
+	a := &fakeAligner{map[string]float64{"q1/P1": 90.0, "q1/P2": 90.0}}
+
+	fa := newTestFannot(queries, entries, s, a)
+	setTestMeta(t, fa, []refdb.Meta{
+		{Access: "P1", Desc: "Protein kinase A", Name: "PKA1", Organism: "Saccharomyces cerevisiae"},
+		{Access: "P2", Desc: "Protein kinase A", Name: "PKA1", Organism: "Saccharomyces cerevisiae", Reviewed: true},
+	})
+	err := runTestFannot(fa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := fa.Results[0]; r.GeneID != "P2" || r.TieBreak != TIE_REVIEWED {
+		t.Errorf("Expected the annotation of q1 from the reviewed P2, found %s (%s).", r.GeneID, r.TieBreak)
+	}
+}
@@ -0,0 +1,252 @@
+package fannot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Issue levels
+const (
+	ISSUE_ERROR   string = "error"
+	ISSUE_WARNING string = "warning"
+)
+
+// Problem found when validating the parameters
+type Issue struct {
+	Level string
+	Where string // Rule set and rule concerned
+	Msg   string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Level, i.Where, i.Msg)
+}
+
+// Indicate if at least one issue is an error
+func HasError(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Level == ISSUE_ERROR {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the exported field names of a structure
+func fieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			names[t.Field(i).Name] = true
+		}
+	}
+	return names
+}
+
+// Check that all the keys of a JSON object are expected fields (the
+// standard decoder also accepts keys with a different case)
+func checkKeys(obj map[string]json.RawMessage, t reflect.Type, where string) error {
+	names := fieldNames(t)
+	for k := range obj {
+		if names[k] {
+			continue
+		}
+		for n := range names {
+			if strings.EqualFold(n, k) {
+				return fmt.Errorf("Unknown field %s in %s (did you mean %s?)", k, where, n)
+			}
+		}
+		return fmt.Errorf("Unknown field %s in %s", k, where)
+	}
+	return nil
+}
+
+func checkRulesKeys(data json.RawMessage, where string) error {
+	var rules []map[string]json.RawMessage
+	err := json.Unmarshal(data, &rules)
+	if err != nil {
+		return fmt.Errorf("Invalid rules in %s: %w", where, err)
+	}
+	for i, r := range rules {
+		err = checkKeys(r, reflect.TypeOf(Rule{}), fmt.Sprintf("%s rule %d", where, i+1))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reject the unknown fields of a rules file
+func checkParamKeys(data []byte) error {
+	var top map[string]json.RawMessage
+	err := json.Unmarshal(data, &top)
+	if err != nil {
+		return err
+	}
+	err = checkKeys(top, reflect.TypeOf(Param{}), "the parameters")
+	if err != nil {
+		return err
+	}
+	if rules, ok := top["Rules"]; ok {
+		err = checkRulesKeys(rules, GLOBAL_RULES)
+		if err != nil {
+			return err
+		}
+	}
+	if dbr, ok := top["Db_rules"]; ok {
+		var sets map[string]map[string]json.RawMessage
+		err = json.Unmarshal(dbr, &sets)
+		if err != nil {
+			return fmt.Errorf("Invalid Db_rules: %w", err)
+		}
+		for db, rs := range sets {
+			err = checkKeys(rs, reflect.TypeOf(RuleSet{}), "the rule set "+db)
+			if err != nil {
+				return err
+			}
+			if rules, ok := rs["Rules"]; ok {
+				err = checkRulesKeys(rules, db)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Indicate if any hit accepted by rule b is also accepted by rule a
+// (considering thresholds only)
+func ruleCovers(a, b *Rule) bool {
+	if a.Cnd_exp != "" {
+		return false
+	}
+	if a.Min_sim > b.Min_sim || a.Min_lra > b.Min_lra || a.Min_idt > b.Min_idt ||
+		a.Min_qcv > b.Min_qcv || a.Min_tcv > b.Min_tcv || a.Min_bsc > b.Min_bsc {
+		return false
+	}
+	if a.Max_eva > 0 && (b.Max_eva == 0 || b.Max_eva > a.Max_eva) {
+		return false
+	}
+	return true
+}
+
+// Validate a list of rules
+func validateRules(name string, rules []Rule) []Issue {
+	issues := make([]Issue, 0)
+	add := func(level, where, format string, a ...interface{}) {
+		issues = append(issues, Issue{level, where, fmt.Sprintf(format, a...)})
+	}
+
+	if len(rules) == 0 {
+		add(ISSUE_WARNING, name, "No rule defined, no annotation will be transferred.")
+	}
+
+	status := make(map[int]int)
+	for i := range rules {
+		r := &rules[i]
+		where := fmt.Sprintf("%s rule %d", name, i+1)
+
+		// Ranges of the thresholds
+		for _, t := range []struct {
+			field string
+			val   float64
+			max   float64
+		}{
+			{"Min_sim", r.Min_sim, 100.0},
+			{"Min_idt", r.Min_idt, 100.0},
+			{"Min_qcv", r.Min_qcv, 100.0},
+			{"Min_tcv", r.Min_tcv, 100.0},
+			{"Min_lra", r.Min_lra, 1.0},
+		} {
+			if t.val < 0.0 || t.val > t.max {
+				add(ISSUE_ERROR, where, "%s must be between 0 and %g, found %g.", t.field, t.max, t.val)
+			}
+		}
+		if r.Max_eva < 0.0 {
+			add(ISSUE_ERROR, where, "Max_eva must be positive, found %g.", r.Max_eva)
+		}
+		if r.Min_bsc < 0.0 {
+			add(ISSUE_ERROR, where, "Min_bsc must be positive, found %g.", r.Min_bsc)
+		}
+
+		// Statuses
+		if r.Hit_sta <= 0 {
+			add(ISSUE_ERROR, where, "Hit_sta must be greater than 0 (the rule would never transfer an annotation), found %d.", r.Hit_sta)
+		}
+		if j, ok := status[r.Hit_sta]; ok {
+			add(ISSUE_ERROR, where, "Hit_sta %d is already used by rule %d.", r.Hit_sta, j+1)
+		} else {
+			status[r.Hit_sta] = i
+		}
+
+		// Expressions
+		if err := r.Compile(); err != nil {
+			add(ISSUE_ERROR, where, "%s.", err)
+		}
+
+		// Comparison with the previous rules
+		for j := 0; j < i; j++ {
+			if ruleCovers(&rules[j], r) {
+				add(ISSUE_ERROR, where, "The rule is unreachable, rule %d accepts all its hits first.", j+1)
+				break
+			}
+		}
+		if i > 0 {
+			p := &rules[i-1]
+			if r.Min_sim > p.Min_sim || r.Min_lra > p.Min_lra {
+				add(ISSUE_WARNING, where, "Rules are not sorted by decreasing stringency (Min_sim %g and Min_lra %g after %g and %g).", r.Min_sim, r.Min_lra, p.Min_sim, p.Min_lra)
+			}
+			if r.Hit_sta > p.Hit_sta {
+				add(ISSUE_WARNING, where, "Rules are not sorted by decreasing status (Hit_sta %d after %d).", r.Hit_sta, p.Hit_sta)
+			}
+		}
+	}
+
+	return issues
+}
+
+// Check the consistency of the parameters
+func (p *Param) Validate() []Issue {
+	issues := make([]Issue, 0)
+	if p.Nbh_chk < 1 {
+		issues = append(issues, Issue{ISSUE_ERROR, GLOBAL_RULES, fmt.Sprintf("Nbh_chk must be at least 1, found %d.", p.Nbh_chk)})
+	}
+	if p.Cns_cfl != CNS_NOTE && p.Cns_cfl != CNS_LOWER {
+		issues = append(issues, Issue{ISSUE_ERROR, GLOBAL_RULES, fmt.Sprintf("Cns_cfl must be %s or %s, found %q.", CNS_NOTE, CNS_LOWER, p.Cns_cfl)})
+	}
+	if p.Dup_nam != DUP_KEEP && p.Dup_nam != DUP_NOTE && p.Dup_nam != DUP_DEMOTE {
+		issues = append(issues, Issue{ISSUE_ERROR, GLOBAL_RULES, fmt.Sprintf("Dup_nam must be %s, %s or %s, found %q.", DUP_KEEP, DUP_NOTE, DUP_DEMOTE, p.Dup_nam)})
+	}
+	for _, c := range p.Tie_brk {
+		if !IsTieBreak(c) {
+			issues = append(issues, Issue{ISSUE_ERROR, GLOBAL_RULES, fmt.Sprintf("Unknown tie-break criterion %q in Tie_brk.", c)})
+		}
+	}
+	issues = append(issues, validateRules(GLOBAL_RULES, p.Rules)...)
+
+	// Rule sets of the DBs (missing values are taken from the global
+	// parameters)
+	dbs := make([]string, 0, len(p.Db_rules))
+	for db := range p.Db_rules {
+		dbs = append(dbs, db)
+	}
+	sort.Strings(dbs)
+	for _, db := range dbs {
+		rs := p.Db_rules[db]
+		if rs.Nbh_chk < 0 {
+			issues = append(issues, Issue{ISSUE_ERROR, db, fmt.Sprintf("Nbh_chk must be at least 1, found %d.", rs.Nbh_chk)})
+		}
+		if len(rs.Rules) == 0 && rs.Nbh_chk == 0 {
+			issues = append(issues, Issue{ISSUE_WARNING, db, "Empty rule set, the global parameters will be used."})
+		}
+		if len(rs.Rules) > 0 {
+			issues = append(issues, validateRules(db, rs.Rules)...)
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,89 @@
+package fannot
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Write a rules file in a temporary directory
+func writeTestRules(t *testing.T, data string) string {
+	file := filepath.Join(t.TempDir(), "rules.json")
+	err := ioutil.WriteFile(file, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestParamUnknownFields(t *testing.T) {
+	tests := map[string]string{
+		`{"Nbh_chk": 3, "Rules": [{"Min_Sim": 80.0, "Hit_sta": 1}]}`: "Min_Sim in global rule 1 (did you mean Min_sim?)",
+		`{"Nbh_check": 3}`: "Nbh_check",
+		`{"Db_rules": {"trembl": {"Rules": [{"Min_sim": 80, "Pre": "x"}]}}}`:     "Pre in trembl rule 1",
+		`{"Db_rules": {"trembl": {"Nbh_chk": 3, "Unk_ann": "unknown protein"}}}`: "Unk_ann in the rule set trembl",
+	}
+	for data, msg := range tests {
+		_, err := NewParamFromJson(writeTestRules(t, data))
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("Expected an error containing %q, found %v.", msg, err)
+		}
+	}
+}
+
+func TestParamValidate(t *testing.T) {
+	// All examples are valid
+	for _, ex := range []string{"three_levels", "uncharacterized", "extended_rules", "db_rules", "expression_rules", "consensus_rules", "ontology_rules"} {
+		p, err := NewParamFromJson("../examples/" + ex + ".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if issues := p.Validate(); len(issues) > 0 {
+			t.Errorf("Unexpected issues in %s: %v.", ex, issues)
+		}
+	}
+
+	p := NewParam()
+	p.Nbh_chk = 0
+	p.Rules = []Rule{
+		{Min_sim: 50.0, Min_lra: 0.7, Hit_sta: 1},
+		{Min_sim: 80.0, Min_lra: 0.8, Hit_sta: 2},
+		{Min_sim: 120.0, Min_lra: 0.5, Max_eva: -1.0, Hit_sta: 1},
+		{Min_sim: 30.0, Min_lra: 0.6, Hit_sta: 0},
+	}
+	p.Db_rules = map[string]RuleSet{"trembl": {}}
+	p.Tie_brk = []string{TIE_EVIDENCE, "organism"}
+
+	exp := []string{
+		"error: global: Nbh_chk must be at least 1",
+		"error: global: Unknown tie-break criterion \"organism\"",
+		"error: global rule 2: The rule is unreachable, rule 1",
+		"warning: global rule 2: Rules are not sorted by decreasing stringency",
+		"warning: global rule 2: Rules are not sorted by decreasing status",
+		"error: global rule 3: Min_sim must be between 0 and 100",
+		"error: global rule 3: Max_eva must be positive",
+		"error: global rule 3: Hit_sta 1 is already used by rule 1",
+		"error: global rule 4: Hit_sta must be greater than 0",
+		"warning: trembl: Empty rule set",
+	}
+	issues := p.Validate()
+	all := make([]string, len(issues))
+	for i, is := range issues {
+		all[i] = is.String()
+	}
+	for _, e := range exp {
+		found := false
+		for _, is := range all {
+			if strings.HasPrefix(is, e) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected the issue %q, found:\n%s", e, strings.Join(all, "\n"))
+		}
+	}
+	if !HasError(issues) {
+		t.Error("Issues should contain errors.")
+	}
+}
@@ -0,0 +1,62 @@
+package flatfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Write a record in the EMBL flat file format
+func WriteEmbl(w io.Writer, r *Record) error {
+	bw := bufio.NewWriter(w)
+
+	// Header
+	fmt.Fprintf(bw, "ID   %s; SV 1; linear; %s; STD; UNC; %d BP.\n", r.Id, MOL_TYPE, len(r.Sequence))
+	fmt.Fprintln(bw, "XX")
+	fmt.Fprintf(bw, "AC   %s;\n", r.Id)
+	fmt.Fprintln(bw, "XX")
+	fmt.Fprintf(bw, "DE   %s\n", r.Desc)
+	fmt.Fprintln(bw, "XX")
+	if r.Organism != "" {
+		fmt.Fprintf(bw, "OS   %s\n", r.Organism)
+		fmt.Fprintln(bw, "XX")
+	}
+
+	// Feature table
+	fmt.Fprintln(bw, "FH   Key             Location/Qualifiers")
+	fmt.Fprintln(bw, "FH")
+	features := append([]Feature{*r.sourceFeature()}, r.Features...)
+	for i := range features {
+		for _, l := range featureLines(&features[i]) {
+			fmt.Fprintf(bw, "FT   %s\n", l)
+		}
+	}
+	fmt.Fprintln(bw, "XX")
+
+	// Sequence (with base composition)
+	var na, nc, ng, nt int
+	for _, b := range r.Sequence {
+		switch b {
+		case 'A', 'a':
+			na++
+		case 'C', 'c':
+			nc++
+		case 'G', 'g':
+			ng++
+		case 'T', 't':
+			nt++
+		}
+	}
+	fmt.Fprintf(bw, "SQ   Sequence %d BP; %d A; %d C; %d G; %d T; %d other;\n",
+		len(r.Sequence), na, nc, ng, nt, len(r.Sequence)-na-nc-ng-nt)
+	for i := 0; i < len(r.Sequence); i += SEQ_LINE_SIZE {
+		end := i + SEQ_LINE_SIZE
+		if end > len(r.Sequence) {
+			end = len(r.Sequence)
+		}
+		fmt.Fprintf(bw, "     %-65s%10d\n", sequenceBlocks(r.Sequence, i), end)
+	}
+	fmt.Fprintln(bw, "//")
+
+	return bw.Flush()
+}
@@ -0,0 +1,187 @@
+package flatfile
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Supported output formats
+const (
+	EMBL_FORMAT    string = "embl"
+	GENBANK_FORMAT string = "genbank"
+)
+
+// Layout of the feature table (INSDC feature table definition)
+const (
+	LINE_WIDTH    int    = 79
+	FEATURE_COL   int    = 21 // Column where locations and qualifiers start
+	SEQ_LINE_SIZE int    = 60
+	SEQ_BLOCK     int    = 10
+	MOL_TYPE      string = "genomic DNA"
+)
+
+// Qualifiers with a value that must not be quoted
+var unquoted = map[string]bool{
+	"codon_start":  true,
+	"transl_table": true,
+}
+
+// Check that a format name is supported
+func IsFormat(f string) bool {
+	return f == EMBL_FORMAT || f == GENBANK_FORMAT
+}
+
+// Write a record in the requested format
+func Write(w io.Writer, r *Record, format string) error {
+	switch strings.ToLower(format) {
+	case EMBL_FORMAT:
+		return WriteEmbl(w, r)
+	case GENBANK_FORMAT:
+		return WriteGenBank(w, r)
+	}
+	return fmt.Errorf("Unknown flat file format: %s.", format)
+}
+
+// Feature interval (1-based, inclusive coordinates)
+type Location struct {
+	Start int
+	End   int
+}
+
+type Qualifier struct {
+	Key   string
+	Value string
+}
+
+type Feature struct {
+	Key        string
+	Complement bool
+	Segments   []Location // Sorted by position
+	Qualifiers []Qualifier
+}
+
+func NewFeature(key string, complement bool) *Feature {
+	return &Feature{Key: key, Complement: complement}
+}
+
+func (f *Feature) AddSegment(start, end int) {
+	f.Segments = append(f.Segments, Location{start, end})
+}
+
+func (f *Feature) AddQualifier(key, value string) {
+	f.Qualifiers = append(f.Qualifiers, Qualifier{key, value})
+}
+
+// Return the INSDC location string (e.g. complement(join(1..10,20..30)))
+func (f *Feature) Location() string {
+	segs := make([]string, len(f.Segments))
+	for i, s := range f.Segments {
+		segs[i] = fmt.Sprintf("%d..%d", s.Start, s.End)
+	}
+	loc := strings.Join(segs, ",")
+	if len(segs) > 1 {
+		loc = "join(" + loc + ")"
+	}
+	if f.Complement {
+		loc = "complement(" + loc + ")"
+	}
+	return loc
+}
+
+// Return the qualifier formatted as /key="value"
+func (q Qualifier) String() string {
+	if unquoted[q.Key] {
+		return "/" + q.Key + "=" + q.Value
+	}
+	// Double quotes are escaped by doubling them
+	return "/" + q.Key + "=\"" + strings.ReplaceAll(q.Value, "\"", "\"\"") + "\""
+}
+
+// Annotated sequence
+type Record struct {
+	Id       string
+	Desc     string
+	Organism string
+	Date     time.Time
+	Sequence []byte
+	Features []Feature
+}
+
+func NewRecord(id string, s []byte) *Record {
+	return &Record{
+		Id:       id,
+		Desc:     id,
+		Date:     time.Now(),
+		Sequence: s,
+	}
+}
+
+// Return the source feature covering the whole sequence
+func (r *Record) sourceFeature() *Feature {
+	f := NewFeature("source", false)
+	f.AddSegment(1, len(r.Sequence))
+	if r.Organism != "" {
+		f.AddQualifier("organism", r.Organism)
+	}
+	f.AddQualifier("mol_type", MOL_TYPE)
+	return f
+}
+
+// Split a feature table value into lines of at most width characters,
+// lines are broken at spaces (dropped) or after commas when possible
+func splitLine(s string, width int) []string {
+	lines := make([]string, 0, 1)
+	for len(s) > width {
+		if i := strings.LastIndexByte(s[:width+1], ' '); i > 0 {
+			lines = append(lines, s[:i])
+			s = s[i+1:]
+		} else if i := strings.LastIndexByte(s[:width], ','); i >= 0 {
+			lines = append(lines, s[:i+1])
+			s = s[i+1:]
+		} else {
+			lines = append(lines, s[:width])
+			s = s[width:]
+		}
+	}
+	return append(lines, s)
+}
+
+// Return the feature table lines of a feature (without line prefix)
+func featureLines(f *Feature) []string {
+	width := LINE_WIDTH - FEATURE_COL
+	pad := strings.Repeat(" ", FEATURE_COL-5)
+	lines := make([]string, 0)
+	for i, l := range splitLine(f.Location(), width) {
+		if i == 0 {
+			lines = append(lines, fmt.Sprintf("%-16s%s", f.Key, l))
+		} else {
+			lines = append(lines, pad+l)
+		}
+	}
+	for _, q := range f.Qualifiers {
+		for _, l := range splitLine(q.String(), width) {
+			lines = append(lines, pad+l)
+		}
+	}
+	return lines
+}
+
+// Split the sequence into lowercase blocks of SEQ_BLOCK residues for
+// the line starting at position i
+func sequenceBlocks(s []byte, i int) string {
+	end := i + SEQ_LINE_SIZE
+	if end > len(s) {
+		end = len(s)
+	}
+	blocks := make([]string, 0, SEQ_LINE_SIZE/SEQ_BLOCK)
+	for j := i; j < end; j += SEQ_BLOCK {
+		k := j + SEQ_BLOCK
+		if k > end {
+			k = end
+		}
+		blocks = append(blocks, strings.ToLower(string(s[j:k])))
+	}
+	return strings.Join(blocks, " ")
+}
@@ -0,0 +1,101 @@
+package flatfile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitLine(t *testing.T) {
+	// Break at spaces (dropped)
+	l := splitLine(`/product="aaa bbb ccc"`, 14)
+	if len(l) != 2 || l[0] != `/product="aaa` || l[1] != `bbb ccc"` {
+		t.Errorf("Unexpected split at spaces: %q.", l)
+	}
+
+	// Break after commas (kept)
+	l = splitLine("join(1..10,20..30,40..50)", 12)
+	if len(l) != 3 || l[0] != "join(1..10," || l[1] != "20..30," {
+		t.Errorf("Unexpected split at commas: %q.", l)
+	}
+
+	// Hard break
+	l = splitLine("abcdefghij", 4)
+	if len(l) != 3 || l[2] != "ij" {
+		t.Errorf("Unexpected hard split: %q.", l)
+	}
+}
+
+func TestWriteRecords(t *testing.T) {
+	r := NewRecord("chr1", []byte(strings.Repeat("ACGT", 20)))
+	r.Organism = "Saccharomyces cerevisiae"
+	r.Date = time.Date(2021, time.March, 5, 0, 0, 0, 0, time.UTC)
+	f := NewFeature("CDS", true)
+	f.AddSegment(1, 10)
+	f.AddSegment(21, 32)
+	f.AddQualifier("codon_start", "2")
+	f.AddQualifier("product", `protein "X"`)
+	r.Features = append(r.Features, *f)
+
+	var gb bytes.Buffer
+	err := Write(&gb, r, GENBANK_FORMAT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []string{
+		"LOCUS       chr1                      80 bp    DNA     linear   UNK 05-MAR-2021\n",
+		"     source          1..80\n                     /organism=\"Saccharomyces cerevisiae\"\n",
+		"     CDS             complement(join(1..10,21..32))\n",
+		"                     /codon_start=2\n",
+		"                     /product=\"protein \"\"X\"\"\"\n",
+		"       61 acgtacgtac gtacgtacgt\n//\n",
+	} {
+		if !strings.Contains(gb.String(), l) {
+			t.Errorf("Expected %q in the GenBank record.", l)
+		}
+	}
+
+	var embl bytes.Buffer
+	err = Write(&embl, r, EMBL_FORMAT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []string{
+		"ID   chr1; SV 1; linear; genomic DNA; STD; UNC; 80 BP.\n",
+		"FT   CDS             complement(join(1..10,21..32))\n",
+		"SQ   Sequence 80 BP; 20 A; 20 C; 20 G; 20 T; 0 other;\n",
+		"     acgtacgtac gtacgtacgt" + strings.Repeat(" ", 52) + "80\n//\n",
+	} {
+		if !strings.Contains(embl.String(), l) {
+			t.Errorf("Expected %q in the EMBL record.", l)
+		}
+	}
+}
+
+func TestWriteTbl(t *testing.T) {
+	r := NewRecord("chr1", nil)
+	g := NewFeature("gene", true)
+	g.AddSegment(1, 32)
+	g.AddQualifier("gene", "PKA1")
+	f := NewFeature("CDS", true)
+	f.AddSegment(1, 10)
+	f.AddSegment(21, 32)
+	f.AddQualifier("product", "protein kinase A")
+	r.Features = append(r.Features, *g, *f)
+
+	var tbl bytes.Buffer
+	err := WriteTbl(&tbl, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := ">Feature chr1\n" +
+		"32\t1\tgene\n" +
+		"\t\t\tgene\tPKA1\n" +
+		"32\t21\tCDS\n" +
+		"10\t1\n" +
+		"\t\t\tproduct\tprotein kinase A\n"
+	if tbl.String() != exp {
+		t.Errorf("Expected:\n%s\nfound:\n%s", exp, tbl.String())
+	}
+}
@@ -0,0 +1,46 @@
+package flatfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Write a record in the GenBank flat file format
+func WriteGenBank(w io.Writer, r *Record) error {
+	bw := bufio.NewWriter(w)
+
+	// Header
+	fmt.Fprintf(bw, "LOCUS       %-16s %11d bp    DNA     linear   UNK %s\n",
+		r.Id, len(r.Sequence), strings.ToUpper(r.Date.Format("02-Jan-2006")))
+	fmt.Fprintf(bw, "DEFINITION  %s.\n", strings.TrimSuffix(r.Desc, "."))
+	fmt.Fprintf(bw, "ACCESSION   %s\n", r.Id)
+	fmt.Fprintf(bw, "VERSION     %s\n", r.Id)
+	fmt.Fprintln(bw, "KEYWORDS    .")
+	org := r.Organism
+	if org == "" {
+		org = "."
+	}
+	fmt.Fprintf(bw, "SOURCE      %s\n", org)
+	fmt.Fprintf(bw, "  ORGANISM  %s\n", org)
+
+	// Feature table
+	fmt.Fprintln(bw, "FEATURES             Location/Qualifiers")
+	features := append([]Feature{*r.sourceFeature()}, r.Features...)
+	for i := range features {
+		for _, l := range featureLines(&features[i]) {
+			fmt.Fprintf(bw, "     %s\n", l)
+		}
+	}
+
+	// Sequence
+	fmt.Fprintln(bw, "ORIGIN")
+	for i := 0; i < len(r.Sequence); i += SEQ_LINE_SIZE {
+		fmt.Fprintf(bw, "%9d %s\n", i+1, sequenceBlocks(r.Sequence, i))
+	}
+	fmt.Fprintln(bw, "//")
+
+	// NOTE: bufio keeps the first write error
+	return bw.Flush()
+}
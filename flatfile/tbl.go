@@ -0,0 +1,38 @@
+package flatfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Write the features of a record as an NCBI five-column feature
+// table (input of table2asn)
+func WriteTbl(w io.Writer, r *Record) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, ">Feature %s\n", r.Id)
+	for _, f := range r.Features {
+		// Segments are listed in the transcription order, start
+		// and stop are swapped on the minus strand
+		n := len(f.Segments)
+		for i := 0; i < n; i++ {
+			s := f.Segments[i]
+			start, end := s.Start, s.End
+			if f.Complement {
+				s = f.Segments[n-1-i]
+				start, end = s.End, s.Start
+			}
+			if i == 0 {
+				fmt.Fprintf(bw, "%d\t%d\t%s\n", start, end, f.Key)
+			} else {
+				fmt.Fprintf(bw, "%d\t%d\n", start, end)
+			}
+		}
+		for _, q := range f.Qualifiers {
+			fmt.Fprintf(bw, "\t\t\t%s\t%s\n", q.Key, q.Value)
+		}
+	}
+
+	return bw.Flush()
+}
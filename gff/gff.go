@@ -0,0 +1,180 @@
+package gff
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Errors returned by the readers
+var (
+	ErrMalformedFeature = errors.New("malformed GFF3 feature")
+)
+
+// GFF3 markers
+const (
+	GFF_VERSION  string = "##gff-version 3"
+	FASTA_MARKER string = "##FASTA"
+	UNDEF_FIELD  string = "."
+)
+
+// Maximal length of a line (the sequences of the FASTA section
+// may not be wrapped)
+const (
+	MAX_LINE_SIZE int = 1 << 30
+)
+
+// Feature attribute (values are stored unescaped)
+type Attribute struct {
+	Key    string
+	Values []string
+}
+
+// Single GFF3 feature line
+type Feature struct {
+	Seqid      string
+	Source     string
+	Type       string
+	Start      int
+	End        int
+	Score      string
+	Strand     string
+	Phase      string
+	Attributes []Attribute // Keep the input order
+}
+
+// Parse a GFF3 feature line
+func ParseFeature(line string) (*Feature, error) {
+	var f Feature
+	var err error
+
+	cols := strings.Split(line, "\t")
+	if len(cols) != 9 {
+		return nil, fmt.Errorf("Expected 9 columns, found %d (%s): %w", len(cols), line, ErrMalformedFeature)
+	}
+
+	f.Seqid = cols[0]
+	f.Source = cols[1]
+	f.Type = cols[2]
+	f.Start, err = strconv.Atoi(cols[3])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid start position (%s): %w", line, ErrMalformedFeature)
+	}
+	f.End, err = strconv.Atoi(cols[4])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid end position (%s): %w", line, ErrMalformedFeature)
+	}
+	f.Score = cols[5]
+	f.Strand = cols[6]
+	f.Phase = cols[7]
+
+	// Parse attributes (tag=value1,value2;...)
+	if cols[8] != UNDEF_FIELD && cols[8] != "" {
+		for _, kv := range strings.Split(cols[8], ";") {
+			kv = strings.TrimSpace(kv)
+			if kv == "" {
+				continue
+			}
+			tmp := strings.SplitN(kv, "=", 2)
+			if len(tmp) != 2 {
+				return nil, fmt.Errorf("Invalid attribute %s (%s): %w", kv, line, ErrMalformedFeature)
+			}
+			a := Attribute{Key: tmp[0]}
+			for _, v := range strings.Split(tmp[1], ",") {
+				uv, err := Unescape(v)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid attribute value %s (%s): %w", v, line, ErrMalformedFeature)
+				}
+				a.Values = append(a.Values, uv)
+			}
+			f.Attributes = append(f.Attributes, a)
+		}
+	}
+
+	return &f, nil
+}
+
+// Format the feature as a GFF3 line
+func (f *Feature) String() string {
+	attr := UNDEF_FIELD
+	if len(f.Attributes) > 0 {
+		kvs := make([]string, len(f.Attributes))
+		for i, a := range f.Attributes {
+			vs := make([]string, len(a.Values))
+			for j, v := range a.Values {
+				vs[j] = Escape(v)
+			}
+			kvs[i] = a.Key + "=" + strings.Join(vs, ",")
+		}
+		attr = strings.Join(kvs, ";")
+	}
+
+	return strings.Join([]string{
+		f.Seqid, f.Source, f.Type,
+		strconv.Itoa(f.Start), strconv.Itoa(f.End),
+		f.Score, f.Strand, f.Phase, attr,
+	}, "\t")
+}
+
+// Return the values of an attribute (nil if not set)
+func (f *Feature) GetAttribute(key string) []string {
+	for _, a := range f.Attributes {
+		if a.Key == key {
+			return a.Values
+		}
+	}
+	return nil
+}
+
+// Set (or replace) the values of an attribute
+func (f *Feature) SetAttribute(key string, values ...string) {
+	for i := range f.Attributes {
+		if f.Attributes[i].Key == key {
+			f.Attributes[i].Values = values
+			return
+		}
+	}
+	f.Attributes = append(f.Attributes, Attribute{Key: key, Values: values})
+}
+
+// Add values to an attribute (already present values are ignored)
+func (f *Feature) AddAttribute(key string, values ...string) {
+	old := f.GetAttribute(key)
+	nvs := append([]string{}, old...)
+VALUES:
+	for _, v := range values {
+		for _, o := range nvs {
+			if o == v {
+				continue VALUES
+			}
+		}
+		nvs = append(nvs, v)
+	}
+	f.SetAttribute(key, nvs...)
+}
+
+// Escape the characters reserved in GFF3 attribute values
+func Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ';' || c == '=' || c == '&' || c == ',' || c == '%' || c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Decode the percent-encoded characters of a GFF3 attribute value
+func Unescape(s string) (string, error) {
+	if !strings.Contains(s, "%") {
+		return s, nil
+	}
+	// NOTE: PathUnescape does not turn '+' into a space
+	return url.PathUnescape(s)
+}
@@ -0,0 +1,179 @@
+package gff
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFeatureRoundTrip(t *testing.T) {
+	line := "chr1\tmaker\tmRNA\t100\t900\t.\t+\t.\tID=g1.t1;Parent=g1;Note=A%3B B%2C C"
+	f, err := ParseFeature(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Start != 100 || f.End != 900 || f.Type != "mRNA" {
+		t.Errorf("Unexpected feature fields: %v.", f)
+	}
+	if v := f.GetAttribute("Note"); len(v) != 1 || v[0] != "A; B, C" {
+		t.Errorf("Expected unescaped Note, found %v.", v)
+	}
+	if f.String() != line {
+		t.Errorf("Expected %s, found %s.", line, f.String())
+	}
+
+	// Replace and add values
+	f.SetAttribute("product", "protein 1=2")
+	f.AddAttribute("Parent", "g1", "g2")
+	if v := f.GetAttribute("Parent"); len(v) != 2 || v[1] != "g2" {
+		t.Errorf("Expected two Parent values, found %v.", v)
+	}
+	exp := "ID=g1.t1;Parent=g1,g2;Note=A%3B B%2C C;product=protein 1%3D2"
+	if got := f.String()[len("chr1\tmaker\tmRNA\t100\t900\t.\t+\t.\t"):]; got != exp {
+		t.Errorf("Expected %s, found %s.", exp, got)
+	}
+}
+
+func TestParseFeatureErrors(t *testing.T) {
+	bad := []string{
+		"chr1\tmaker\tmRNA\t100\t900",
+		"chr1\tmaker\tmRNA\tA\t900\t.\t+\t.\tID=g1",
+		"chr1\tmaker\tmRNA\t100\t900\t.\t+\t.\tID",
+	}
+	for _, line := range bad {
+		_, err := ParseFeature(line)
+		if !errors.Is(err, ErrMalformedFeature) {
+			t.Errorf("Expected ErrMalformedFeature for %q, found %v.", line, err)
+		}
+	}
+}
+
+func TestLoadGenes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "genes.gff3")
+	data := strings.Join([]string{
+		"##gff-version 3",
+		// Children before their parent
+		"chr1\tmaker\tCDS\t200\t250\t.\t-\t2\tID=g1.t1.cds;Parent=g1.t1;protein_id=P_g1",
+		"chr1\tmaker\tCDS\t100\t150\t.\t-\t0\tID=g1.t1.cds;Parent=g1.t1;protein_id=P_g1",
+		"chr1\tmaker\tmRNA\t90\t260\t.\t-\t.\tID=g1.t1;Parent=g1",
+		"chr1\tmaker\texon\t90\t150\t.\t-\t.\tParent=g1.t1",
+		"chr1\tmaker\texon\t200\t260\t.\t-\t.\tParent=g1.t1",
+		"chr1\tmaker\tgene\t90\t260\t.\t-\t.\tID=g1",
+		// Prokaryote-like gene (no mRNA)
+		"chr2\tprodigal\tgene\t1\t300\t.\t+\t.\tID=g2",
+		"chr2\tprodigal\tCDS\t1\t300\t.\t+\t0\tID=g2.cds;Parent=g2",
+		// Non-coding gene (unsupported transcript type)
+		"chr2\tmaker\tgene\t400\t472\t.\t+\t.\tID=g3",
+		"chr2\tmaker\ttRNA\t400\t472\t.\t+\t.\tID=g3.t1;Parent=g3",
+		"chr2\tmaker\texon\t400\t472\t.\t+\t.\tParent=g3.t1",
+	}, "\n") + "\n"
+	err := ioutil.WriteFile(file, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genes, err := LoadGenes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(genes) != 3 || genes[0].Id != "g1" || genes[1].Id != "g2" {
+		t.Fatalf("Unexpected genes: %v.", genes)
+	}
+	tr := genes[0].Transcripts[0]
+	if tr.Id != "g1.t1" || tr.ProteinId != "P_g1" || len(tr.Exons) != 2 || len(tr.Cds) != 2 {
+		t.Errorf("Unexpected transcript: %v.", tr)
+	}
+	if tr.Cds[0].Start != 100 || genes[0].CdsPhase(tr) != 2 {
+		t.Errorf("CDS are not sorted or the phase is wrong: %v.", tr.Cds)
+	}
+	if len(genes[1].Transcripts) != 1 || genes[1].Transcripts[0].CdsId != "g2.cds" {
+		t.Errorf("Expected an implicit transcript for g2, found %v.", genes[1].Transcripts)
+	}
+	if len(genes[2].Transcripts) != 0 {
+		t.Errorf("The tRNA of g3 should be skipped, found %v.", genes[2].Transcripts)
+	}
+}
+
+// Sequences of the FASTA section may be on a single (unwrapped) line
+func TestReaderLongLine(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "genes.gff3")
+	sq := strings.Repeat("ACGT", 30000)
+	data := strings.Join([]string{
+		"##gff-version 3",
+		"chr1\tmaker\tgene\t1\t90\t.\t+\t.\tID=g1",
+		FASTA_MARKER,
+		">chr1",
+		sq,
+	}, "\n") + "\n"
+	err := ioutil.WriteFile(file, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	last := ""
+	for r.Next() {
+		last = r.Line()
+	}
+	if r.Err() != nil {
+		t.Fatal(r.Err())
+	}
+	if last != sq {
+		t.Errorf("Expected the whole sequence line (%d bp), found %d bp.", len(sq), len(last))
+	}
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "genes.gff3.gz")
+	lines := []string{
+		"##gff-version 3",
+		"chr1\tmaker\tgene\t1\t90\t.\t+\t.\tID=g1",
+	}
+
+	w, err := NewWriter(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range lines {
+		err = w.WriteLine(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.file.Stat(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected the compressed file to be closed by the writer, got %v.", err)
+	}
+
+	r, err := NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := make([]string, 0)
+	for r.Next() {
+		read = append(read, r.Line())
+	}
+	if r.Err() != nil {
+		t.Fatal(r.Err())
+	}
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(read, "\n") != strings.Join(lines, "\n") {
+		t.Errorf("Unexpected lines read from the compressed file: %v.", read)
+	}
+	if _, err = r.file.Stat(); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected the compressed file to be closed by the reader, got %v.", err)
+	}
+}
@@ -0,0 +1,170 @@
+package gff
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Feature types used to build gene models
+var (
+	geneTypes       = map[string]bool{"gene": true, "pseudogene": true}
+	transcriptTypes = map[string]bool{"mRNA": true, "transcript": true}
+)
+
+// Segment of a feature (1-based, inclusive coordinates)
+type Segment struct {
+	Start int
+	End   int
+	Phase int // CDS phase (0 if undefined)
+}
+
+type Transcript struct {
+	Id        string
+	CdsId     string
+	ProteinId string
+	Exons     []Segment
+	Cds       []Segment
+}
+
+type Gene struct {
+	Id          string
+	Seqid       string
+	Start       int
+	End         int
+	Strand      string
+	Transcripts []*Transcript
+}
+
+// Return the phase of the first CDS segment in the transcription
+// direction
+func (g *Gene) CdsPhase(t *Transcript) int {
+	if len(t.Cds) == 0 {
+		return 0
+	}
+	if g.Strand == "-" {
+		return t.Cds[len(t.Cds)-1].Phase
+	}
+	return t.Cds[0].Phase
+}
+
+func newSegment(f *Feature) Segment {
+	s := Segment{Start: f.Start, End: f.End}
+	switch f.Phase {
+	case "1":
+		s.Phase = 1
+	case "2":
+		s.Phase = 2
+	}
+	return s
+}
+
+func sortSegments(s []Segment) {
+	sort.Slice(s, func(i, j int) bool { return s[i].Start < s[j].Start })
+}
+
+func firstAttribute(f *Feature, key string) string {
+	if v := f.GetAttribute(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Load the gene models (gene > mRNA > exon/CDS) of a GFF3 file,
+// genes are returned in the file order (the exons of other transcript
+// types, e.g. tRNA or rRNA, are skipped)
+func LoadGenes(file string) ([]*Gene, error) {
+	r, err := NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// Children may appear before their parent, collect everything first
+	genes := make([]*Gene, 0)
+	geneIdx := make(map[string]*Gene)
+	trans := make(map[string]*Transcript)
+	tids := make([]string, 0)
+	transGene := make(map[string]string)
+	children := make([]*Feature, 0)
+	others := make(map[string]bool) // Unsupported transcripts (tRNA, rRNA...)
+	for r.Next() {
+		f := r.Feature()
+		if f == nil {
+			continue
+		}
+		id := firstAttribute(f, "ID")
+		switch {
+		case geneTypes[f.Type]:
+			if id == "" {
+				return nil, fmt.Errorf("Gene without ID at %s:%d: %w", f.Seqid, f.Start, ErrMalformedFeature)
+			}
+			g := &Gene{Id: id, Seqid: f.Seqid, Start: f.Start, End: f.End, Strand: f.Strand}
+			genes = append(genes, g)
+			geneIdx[id] = g
+		case transcriptTypes[f.Type]:
+			if id == "" {
+				return nil, fmt.Errorf("Transcript without ID at %s:%d: %w", f.Seqid, f.Start, ErrMalformedFeature)
+			}
+			trans[id] = &Transcript{Id: id}
+			tids = append(tids, id)
+			transGene[id] = firstAttribute(f, "Parent")
+		case f.Type == "exon" || f.Type == "CDS":
+			children = append(children, f)
+		case id != "":
+			others[id] = true
+		}
+	}
+	if r.Err() != nil {
+		return nil, fmt.Errorf("Failed to read the GFF3 file %s: %w", file, r.Err())
+	}
+
+	// Attach transcripts to their gene (keep the file order)
+	for _, tid := range tids {
+		g, ok := geneIdx[transGene[tid]]
+		if !ok {
+			return nil, fmt.Errorf("Parent gene %s of the transcript %s not found: %w", transGene[tid], tid, ErrMalformedFeature)
+		}
+		g.Transcripts = append(g.Transcripts, trans[tid])
+	}
+
+	// Attach exons and CDS to their transcript(s)
+	for _, f := range children {
+		for _, pid := range f.GetAttribute("Parent") {
+			t, ok := trans[pid]
+			if !ok && others[pid] {
+				// Child of an unsupported transcript type
+				continue
+			}
+			if !ok {
+				// CDS directly attached to a gene (no mRNA)
+				g, gok := geneIdx[pid]
+				if !gok || f.Type != "CDS" {
+					return nil, fmt.Errorf("Parent %s of the %s at %s:%d not found: %w", pid, f.Type, f.Seqid, f.Start, ErrMalformedFeature)
+				}
+				if len(g.Transcripts) == 0 {
+					g.Transcripts = append(g.Transcripts, &Transcript{Id: g.Id})
+				}
+				t = g.Transcripts[0]
+			}
+			if f.Type == "exon" {
+				t.Exons = append(t.Exons, newSegment(f))
+			} else {
+				t.Cds = append(t.Cds, newSegment(f))
+				if t.CdsId == "" {
+					t.CdsId = firstAttribute(f, "ID")
+				}
+				if t.ProteinId == "" {
+					t.ProteinId = firstAttribute(f, "protein_id")
+				}
+			}
+		}
+	}
+	for _, g := range genes {
+		for _, t := range g.Transcripts {
+			sortSegments(t.Exons)
+			sortSegments(t.Cds)
+		}
+	}
+
+	return genes, nil
+}
@@ -0,0 +1,106 @@
+package gff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	gzip "github.com/klauspost/pgzip"
+)
+
+type Reader struct {
+	file    *os.File
+	gz      io.Closer // Decompressor (nil without compression)
+	scanner *bufio.Scanner
+	line    string
+	feature *Feature
+	fasta   bool // The embedded FASTA section has been reached
+	err     error
+	nline   int
+}
+
+func NewReader(file string) (*Reader, error) {
+	// Open the file
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the file has a 'gz' extention, then use zlib
+	r := Reader{file: f}
+	if regexp.MustCompile(`\.gz$`).MatchString(file) {
+		fgzip, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		r.gz = fgzip
+		r.scanner = bufio.NewScanner(fgzip)
+	} else {
+		r.scanner = bufio.NewScanner(f)
+	}
+	r.scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), MAX_LINE_SIZE)
+
+	return &r, nil
+}
+
+// Read the next line, features are parsed until the FASTA section
+func (r *Reader) Next() bool {
+	r.feature = nil
+	if !r.scanner.Scan() {
+		r.err = r.scanner.Err()
+		return false
+	}
+	r.line = r.scanner.Text()
+	r.nline++
+
+	if r.fasta {
+		return true
+	}
+	if strings.HasPrefix(r.line, FASTA_MARKER) || strings.HasPrefix(r.line, ">") {
+		r.fasta = true
+		return true
+	}
+
+	// Comments, directives and blank lines are kept as is
+	if r.line == "" || strings.HasPrefix(r.line, "#") {
+		return true
+	}
+
+	r.feature, r.err = ParseFeature(r.line)
+	if r.err != nil {
+		r.err = fmt.Errorf("Line %d: %w", r.nline, r.err)
+		return false
+	}
+	return true
+}
+
+// Close the decompressor and the file (the first error is returned)
+func (r *Reader) Close() error {
+	var err error
+	if r.gz != nil {
+		err = r.gz.Close()
+	}
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Return the error that stopped the reading (nil at EOF)
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Return the raw current line
+func (r *Reader) Line() string {
+	return r.line
+}
+
+// Return the current feature (nil if the line is not a feature)
+func (r *Reader) Feature() *Feature {
+	return r.feature
+}
@@ -0,0 +1,58 @@
+package gff
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+
+	gzip "github.com/klauspost/pgzip"
+)
+
+// Writer of a GFF3 file, compressed if the file name ends with .gz
+type Writer struct {
+	file   *os.File
+	gz     io.WriteCloser // Compressor (nil without compression)
+	writer *bufio.Writer
+}
+
+func NewWriter(file string) (*Writer, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the provided file has a 'gz' extention, then compress
+	w := Writer{file: f}
+	if regexp.MustCompile(`\.gz$`).MatchString(file) {
+		w.gz = gzip.NewWriter(f)
+		w.writer = bufio.NewWriter(w.gz)
+	} else {
+		w.writer = bufio.NewWriter(f)
+	}
+	return &w, nil
+}
+
+// Flush the buffer, close the compressor and the file (the first error
+// is returned)
+func (w *Writer) Close() error {
+	err := w.writer.Flush()
+	if w.gz != nil {
+		if cerr := w.gz.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (w *Writer) WriteLine(s string) error {
+	_, err := w.writer.Write([]byte(s + "\n"))
+	return err
+}
+
+func (w *Writer) WriteFeature(f *Feature) error {
+	return w.WriteLine(f.String())
+}
@@ -0,0 +1,166 @@
+package refdb
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hdevillers/go-seq/seq"
+)
+
+const (
+	FASTA_INDEX_PATH string = "protein.fasta.fai"
+)
+
+// Errors returned when reading the sequences of the entries
+var (
+	ErrSeqNotFound    = errors.New("entry missing from the FASTA index")
+	ErrMalformedFasta = errors.New("malformed FASTA file or index")
+)
+
+// Location of a sequence in the FASTA file (see samtools faidx)
+type faiEntry struct {
+	length    int   // Number of residues
+	offset    int64 // Offset of the first residue
+	lineBases int   // Number of residues per line
+	lineWidth int   // Number of bytes per line (with the new line)
+}
+
+// Write the samtools faidx index of a FASTA file (lines of a sequence
+// must have the same length, except the last one)
+func IndexFasta(file, index string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	o, err := os.Create(index)
+	if err != nil {
+		return err
+	}
+	defer o.Close()
+	ow := bufio.NewWriter(o)
+
+	var id string
+	var fe faiEntry
+	var offset int64
+	last := false // The last line of the sequence has been read
+	write := func() error {
+		if id == "" {
+			return nil
+		}
+		if fe.length == 0 {
+			return fmt.Errorf("Empty sequence %s in %s: %w", id, file, ErrMalformedFasta)
+		}
+		_, err := fmt.Fprintf(ow, "%s\t%d\t%d\t%d\t%d\n", id, fe.length, fe.offset, fe.lineBases, fe.lineWidth)
+		return err
+	}
+
+	fr := bufio.NewReader(f)
+	for {
+		line, err := fr.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			break
+		}
+		width := len(line)
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 && line[0] == '>' {
+			if err := write(); err != nil {
+				return err
+			}
+			id = strings.SplitN(string(line[1:]), " ", 2)[0]
+			fe = faiEntry{offset: offset + int64(width)}
+			last = false
+		} else if len(line) > 0 {
+			if id == "" {
+				return fmt.Errorf("Sequence without ID in %s: %w", file, ErrMalformedFasta)
+			}
+			if last || (fe.lineBases > 0 && len(line) > fe.lineBases) {
+				return fmt.Errorf("Unequal line lengths in the sequence %s of %s: %w", id, file, ErrMalformedFasta)
+			}
+			if fe.lineBases == 0 {
+				fe.lineBases = len(line)
+				fe.lineWidth = width
+			} else if len(line) < fe.lineBases || width < fe.lineWidth {
+				last = true
+			}
+			fe.length += len(line)
+		}
+		offset += int64(width)
+		if err != nil {
+			break
+		}
+	}
+	if err := write(); err != nil {
+		return err
+	}
+
+	return ow.Flush()
+}
+
+// Random access reader of the sequences of a FASTA file (safe for
+// concurrent use)
+type SeqStore struct {
+	file  *os.File
+	index *entryIndex
+}
+
+// Open the FASTA file and the entry index of the DB
+func (r *Refdb) OpenSeqs() (*SeqStore, error) {
+	ei, err := openEntryIndex(r.EntryIndex)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(r.Fasta)
+	if err != nil {
+		ei.Close()
+		return nil, err
+	}
+	return &SeqStore{file: f, index: ei}, nil
+}
+
+// Read the sequence of an entry (the description is not read)
+func (ss *SeqStore) Get(id string) (seq.Seq, error) {
+	el, ok, err := ss.index.find(id)
+	if err != nil {
+		return seq.Seq{}, err
+	}
+	fe := el.seq
+	if !ok || fe.length == 0 {
+		return seq.Seq{}, fmt.Errorf("Failed to find the entry %s: %w", id, ErrSeqNotFound)
+	}
+
+	// Read the complete lines and the residues of the last one (without
+	// its new line)
+	full := (fe.length - 1) / fe.lineBases
+	size := full*fe.lineWidth + fe.length - full*fe.lineBases
+	buf := make([]byte, size)
+	_, err = ss.file.ReadAt(buf, fe.offset)
+	if err != nil {
+		return seq.Seq{}, fmt.Errorf("Failed to read the sequence of the entry %s: %w", id, err)
+	}
+	res := make([]byte, 0, fe.length)
+	for _, b := range buf {
+		if b != '\n' && b != '\r' {
+			res = append(res, b)
+		}
+	}
+	if len(res) != fe.length {
+		return seq.Seq{}, fmt.Errorf("Unexpected length of the sequence of the entry %s: %w", id, ErrMalformedFasta)
+	}
+
+	s := seq.NewSeq(id)
+	s.Sequence = res
+	return *s, nil
+}
+
+func (ss *SeqStore) Close() error {
+	err := ss.file.Close()
+	if cerr := ss.index.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
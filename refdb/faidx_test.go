@@ -0,0 +1,84 @@
+package refdb
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hdevillers/go-seq/seq"
+	"github.com/hdevillers/go-seq/seqio/fasta"
+)
+
+func TestSeqStore(t *testing.T) {
+	dir := t.TempDir()
+	r := Refdb{Fasta: dir + "/" + FASTA_PATH, FastaIndex: dir + "/" + FASTA_INDEX_PATH, EntryIndex: dir + "/" + ENTRY_INDEX_PATH}
+
+	// Sequences shorter than, equal to and longer than a FASTA line
+	seqs := map[string]string{
+		"P1": "MKVLAGT",
+		"P2": strings.Repeat("A", fasta.LineLength),
+		"P3": strings.Repeat("MKVLAGTW", 20),
+	}
+	f, err := os.Create(r.Fasta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw := fasta.NewWriter(bufio.NewWriter(f))
+	for _, id := range []string{"P1", "P2", "P3"} {
+		s := seq.NewSeq(id)
+		s.Desc = "Protein " + id
+		s.Sequence = []byte(seqs[id])
+		err = fw.Write(*s)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	err = IndexFasta(r.Fasta, r.FastaIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteEntryIndex(r.FastaIndex, "", r.EntryIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, err := r.OpenSeqs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close()
+
+	// Random access, in any order
+	for _, id := range []string{"P3", "P1", "P2"} {
+		s, err := ss.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Id != id || string(s.Sequence) != seqs[id] {
+			t.Errorf("Unexpected sequence of %s: %s.", id, s.Sequence)
+		}
+	}
+
+	_, err = ss.Get("P4")
+	if !errors.Is(err, ErrSeqNotFound) {
+		t.Errorf("Expected ErrSeqNotFound for a missing entry, got %v.", err)
+	}
+}
+
+func TestIndexFastaUnequalLines(t *testing.T) {
+	dir := t.TempDir()
+	file := dir + "/" + FASTA_PATH
+	err := ioutil.WriteFile(file, []byte(">P1\nMKV\nMKVLAGT\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = IndexFasta(file, dir+"/"+FASTA_INDEX_PATH)
+	if !errors.Is(err, ErrMalformedFasta) {
+		t.Errorf("Expected ErrMalformedFasta for unequal line lengths, got %v.", err)
+	}
+}
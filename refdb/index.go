@@ -0,0 +1,430 @@
+package refdb
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ENTRY_INDEX_PATH  string = "entries.idx"
+	ENTRY_INDEX_MAGIC string = "FAEI"
+	ENTRY_HEADER_SIZE int    = 8  // Magic and accession width
+	ENTRY_LOC_SIZE    int    = 32 // Locations of the sequence and of the metadata
+)
+
+// Number of records sorted in memory before being written to a
+// temporary run when building the index of the entries
+var entryRunSize int = 1 << 20
+
+// Error returned when reading the index of the entries
+var ErrMalformedIndex = errors.New("malformed entry index")
+
+// Location of an entry in the FASTA file and in the metadata store (a
+// zero length means the entry is missing from the file)
+type entryLoc struct {
+	seq  faiEntry
+	meta metaOffset
+}
+
+func (el *entryLoc) encode(b []byte) {
+	le := binary.LittleEndian
+	le.PutUint64(b[0:], uint64(el.seq.offset))
+	le.PutUint32(b[8:], uint32(el.seq.length))
+	le.PutUint32(b[12:], uint32(el.seq.lineBases))
+	le.PutUint32(b[16:], uint32(el.seq.lineWidth))
+	le.PutUint64(b[20:], uint64(el.meta.offset))
+	le.PutUint32(b[28:], uint32(el.meta.length))
+}
+
+func (el *entryLoc) decode(b []byte) {
+	le := binary.LittleEndian
+	el.seq.offset = int64(le.Uint64(b[0:]))
+	el.seq.length = int(le.Uint32(b[8:]))
+	el.seq.lineBases = int(le.Uint32(b[12:]))
+	el.seq.lineWidth = int(le.Uint32(b[16:]))
+	el.meta.offset = int64(le.Uint64(b[20:]))
+	el.meta.length = int(le.Uint32(b[28:]))
+}
+
+// Fixed size records (accession padded with zeros and locations) sorted
+// by accession
+type entryRecords struct {
+	data  []byte
+	width int // Width of the accessions
+	size  int // Size of a record
+	tmp   []byte
+}
+
+func (er *entryRecords) Len() int { return len(er.data) / er.size }
+
+func (er *entryRecords) key(i int) []byte {
+	return er.data[i*er.size : i*er.size+er.width]
+}
+
+func (er *entryRecords) Less(i, j int) bool {
+	return bytes.Compare(er.key(i), er.key(j)) < 0
+}
+
+func (er *entryRecords) Swap(i, j int) {
+	a := er.data[i*er.size : (i+1)*er.size]
+	b := er.data[j*er.size : (j+1)*er.size]
+	copy(er.tmp, a)
+	copy(a, b)
+	copy(b, er.tmp)
+}
+
+// Read the lines (accession first, tab separated) of a text index
+func scanIndex(file string, nf int, fn func(fields []string) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	is := bufio.NewScanner(f)
+	for is.Scan() {
+		fields := strings.Split(is.Text(), "\t")
+		if len(fields) != nf || fields[0] == "" {
+			return fmt.Errorf("Unexpected line in the index %s (%s): %w", file, is.Text(), ErrMalformedIndex)
+		}
+		err = fn(fields)
+		if err != nil {
+			return fmt.Errorf("Failed to index the line %s of %s: %w", is.Text(), file, err)
+		}
+	}
+	return is.Err()
+}
+
+// Parse the values of a line of a FASTA index (faidx)
+func parseFai(fields []string) (faiEntry, error) {
+	var fe faiEntry
+	var err error
+	fe.length, err = strconv.Atoi(fields[1])
+	if err == nil {
+		fe.offset, err = strconv.ParseInt(fields[2], 10, 64)
+	}
+	if err == nil {
+		fe.lineBases, err = strconv.Atoi(fields[3])
+	}
+	if err == nil {
+		fe.lineWidth, err = strconv.Atoi(fields[4])
+	}
+	if err != nil || fe.length <= 0 || fe.lineBases <= 0 {
+		return fe, ErrMalformedIndex
+	}
+	return fe, nil
+}
+
+// Parse the values of a line of a metadata index
+func parseMetaIdx(fields []string) (metaOffset, error) {
+	var mo metaOffset
+	var err error
+	mo.offset, err = strconv.ParseInt(fields[1], 10, 64)
+	if err == nil {
+		mo.length, err = strconv.Atoi(fields[2])
+	}
+	if err != nil || mo.length <= 0 {
+		return mo, ErrMalformedIndex
+	}
+	return mo, nil
+}
+
+// Write the sorted index of the entries from the FASTA index (faidx)
+// and from the metadata index (an empty path skips an index)
+func WriteEntryIndex(fai, meta, index string) error {
+	// The accession width is required to build the records
+	width := 0
+	for _, t := range []struct {
+		file string
+		nf   int
+	}{{fai, 5}, {meta, 3}} {
+		if t.file == "" {
+			continue
+		}
+		err := scanIndex(t.file, t.nf, func(fields []string) error {
+			if len(fields[0]) > width {
+				width = len(fields[0])
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The records are sorted by chunks written to temporary runs
+	er := entryRecords{width: width, size: width + ENTRY_LOC_SIZE}
+	er.tmp = make([]byte, er.size)
+	var runs []string
+	defer func() {
+		for _, run := range runs {
+			os.Remove(run)
+		}
+	}()
+	spill := func() error {
+		if len(er.data) == 0 {
+			return nil
+		}
+		sort.Sort(&er)
+		f, err := os.CreateTemp(filepath.Dir(index), "entries-*.run")
+		if err != nil {
+			return err
+		}
+		runs = append(runs, f.Name())
+		_, err = f.Write(er.data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		er.data = er.data[:0]
+		return err
+	}
+	add := func(id string, el entryLoc) error {
+		n := len(er.data)
+		er.data = append(er.data, make([]byte, er.size)...)
+		copy(er.data[n:], id)
+		el.encode(er.data[n+width:])
+		if er.Len() >= entryRunSize {
+			return spill()
+		}
+		return nil
+	}
+	if fai != "" {
+		err := scanIndex(fai, 5, func(fields []string) error {
+			fe, err := parseFai(fields)
+			if err != nil {
+				return err
+			}
+			return add(fields[0], entryLoc{seq: fe})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if meta != "" {
+		err := scanIndex(meta, 3, func(fields []string) error {
+			mo, err := parseMetaIdx(fields)
+			if err != nil {
+				return err
+			}
+			return add(fields[0], entryLoc{meta: mo})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err := spill()
+	if err != nil {
+		return err
+	}
+	er.data = nil
+
+	// Merge the runs, the locations of an entry are gathered in a single
+	// record
+	eh := entryHeap{width: width, size: er.size}
+	for _, name := range runs {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		run := &entryRun{r: bufio.NewReader(f), rec: make([]byte, eh.size)}
+		ok, err := run.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			eh.runs = append(eh.runs, run)
+		}
+	}
+	heap.Init(&eh)
+
+	o, err := os.Create(index)
+	if err != nil {
+		return err
+	}
+	defer o.Close()
+	ow := bufio.NewWriter(o)
+	header := make([]byte, ENTRY_HEADER_SIZE)
+	copy(header, ENTRY_INDEX_MAGIC)
+	binary.LittleEndian.PutUint32(header[4:], uint32(width))
+	_, err = ow.Write(header)
+	if err != nil {
+		return err
+	}
+	cur := make([]byte, eh.size)
+	found := false
+	for eh.Len() > 0 {
+		run := eh.runs[0]
+		if found && bytes.Equal(cur[:width], run.rec[:width]) {
+			err = mergeEntryLocs(cur[width:], run.rec[width:])
+			if err != nil {
+				return fmt.Errorf("Duplicated entry %s: %w", bytes.TrimRight(cur[:width], "\x00"), err)
+			}
+		} else {
+			if found {
+				_, err = ow.Write(cur)
+				if err != nil {
+					return err
+				}
+			}
+			copy(cur, run.rec)
+			found = true
+		}
+		ok, err := run.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&eh, 0)
+		} else {
+			heap.Pop(&eh)
+		}
+	}
+	if found {
+		_, err = ow.Write(cur)
+		if err != nil {
+			return err
+		}
+	}
+	err = ow.Flush()
+	if err != nil {
+		return err
+	}
+	return o.Close()
+}
+
+// Gather the locations of an entry found in two records
+func mergeEntryLocs(dst, src []byte) error {
+	var a, b entryLoc
+	a.decode(dst)
+	b.decode(src)
+	if (a.seq.length > 0 && b.seq.length > 0) || (a.meta.length > 0 && b.meta.length > 0) {
+		return ErrMalformedIndex
+	}
+	if b.seq.length > 0 {
+		a.seq = b.seq
+	} else {
+		a.meta = b.meta
+	}
+	a.encode(dst)
+	return nil
+}
+
+// Sorted run of records being merged
+type entryRun struct {
+	r   *bufio.Reader
+	rec []byte // Current record
+}
+
+// Read the next record of the run (false at the end of the run)
+func (er *entryRun) next() (bool, error) {
+	_, err := io.ReadFull(er.r, er.rec)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Failed to read a sorted run of the entry index: %w", err)
+	}
+	return true, nil
+}
+
+// Runs ordered by their current record
+type entryHeap struct {
+	runs  []*entryRun
+	width int // Width of the accessions
+	size  int // Size of a record
+}
+
+func (eh *entryHeap) Len() int { return len(eh.runs) }
+
+func (eh *entryHeap) Less(i, j int) bool {
+	return bytes.Compare(eh.runs[i].rec[:eh.width], eh.runs[j].rec[:eh.width]) < 0
+}
+
+func (eh *entryHeap) Swap(i, j int) { eh.runs[i], eh.runs[j] = eh.runs[j], eh.runs[i] }
+
+func (eh *entryHeap) Push(x interface{}) { eh.runs = append(eh.runs, x.(*entryRun)) }
+
+func (eh *entryHeap) Pop() interface{} {
+	run := eh.runs[len(eh.runs)-1]
+	eh.runs = eh.runs[:len(eh.runs)-1]
+	return run
+}
+
+// Reader of the sorted index of the entries, the entries are searched
+// in the file (safe for concurrent use)
+type entryIndex struct {
+	file  *os.File
+	width int // Width of the accessions
+	size  int // Size of a record
+	count int // Number of records
+}
+
+func openEntryIndex(index string) (*entryIndex, error) {
+	f, err := os.Open(index)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	header := make([]byte, ENTRY_HEADER_SIZE)
+	_, err = io.ReadFull(f, header)
+	if err != nil || string(header[:4]) != ENTRY_INDEX_MAGIC {
+		f.Close()
+		return nil, fmt.Errorf("Unexpected header in the index %s: %w", index, ErrMalformedIndex)
+	}
+	ei := entryIndex{file: f, width: int(binary.LittleEndian.Uint32(header[4:]))}
+	ei.size = ei.width + ENTRY_LOC_SIZE
+	body := fi.Size() - int64(ENTRY_HEADER_SIZE)
+	if body%int64(ei.size) != 0 {
+		f.Close()
+		return nil, fmt.Errorf("Unexpected size of the index %s: %w", index, ErrMalformedIndex)
+	}
+	ei.count = int(body / int64(ei.size))
+	return &ei, nil
+}
+
+// Search the location of an entry (binary search over the records)
+func (ei *entryIndex) find(id string) (entryLoc, bool, error) {
+	var el entryLoc
+	if id == "" || len(id) > ei.width {
+		return el, false, nil
+	}
+	key := make([]byte, ei.width)
+	copy(key, id)
+	rec := make([]byte, ei.size)
+	lo, hi := 0, ei.count
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		_, err := ei.file.ReadAt(rec, int64(ENTRY_HEADER_SIZE)+int64(mid)*int64(ei.size))
+		if err != nil {
+			return el, false, fmt.Errorf("Failed to read the entry index: %w", err)
+		}
+		switch c := bytes.Compare(rec[:ei.width], key); {
+		case c < 0:
+			lo = mid + 1
+		case c > 0:
+			hi = mid
+		default:
+			el.decode(rec[ei.width:])
+			return el, true, nil
+		}
+	}
+	return el, false, nil
+}
+
+func (ei *entryIndex) Close() error {
+	return ei.file.Close()
+}
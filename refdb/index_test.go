@@ -0,0 +1,156 @@
+package refdb
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestEntryIndex(t *testing.T) {
+	dir := t.TempDir()
+	fai := dir + "/" + FASTA_INDEX_PATH
+	meta := dir + "/" + META_INDEX_PATH
+	index := dir + "/" + ENTRY_INDEX_PATH
+
+	// Unsorted accessions of different widths, P3 has no metadata and
+	// A0A024R161 has no sequence
+	err := ioutil.WriteFile(fai, []byte("P2\t7\t20\t60\t61\nP10\t120\t40\t60\t61\nP3\t3\t200\t60\t61\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(meta, []byte("P2\t0\t50\nP10\t50\t60\nA0A024R161\t110\t70\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteEntryIndex(fai, meta, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ei, err := openEntryIndex(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ei.Close()
+	if ei.count != 4 || ei.width != 10 {
+		t.Errorf("Expected 4 records of width 10, got %d of width %d.", ei.count, ei.width)
+	}
+
+	cases := []struct {
+		id        string
+		found     bool
+		seqOffset int64
+		seqLength int
+		metaLen   int
+	}{
+		{"P2", true, 20, 7, 50},
+		{"P10", true, 40, 120, 60},
+		{"P3", true, 200, 3, 0},
+		{"A0A024R161", true, 0, 0, 70},
+		{"P1", false, 0, 0, 0},
+		{"P99", false, 0, 0, 0},
+		{"A0A024R161X", false, 0, 0, 0},
+	}
+	for _, c := range cases {
+		el, ok, err := ei.find(c.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != c.found || el.seq.offset != c.seqOffset || el.seq.length != c.seqLength || el.meta.length != c.metaLen {
+			t.Errorf("Unexpected location of %s: %v, %+v.", c.id, ok, el)
+		}
+	}
+}
+
+func TestEntryIndexDuplicate(t *testing.T) {
+	dir := t.TempDir()
+	meta := dir + "/" + META_INDEX_PATH
+	err := ioutil.WriteFile(meta, []byte("P1\t0\t50\nP1\t50\t60\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// In the same run and in different runs
+	defer func(n int) { entryRunSize = n }(entryRunSize)
+	for _, n := range []int{10, 1} {
+		entryRunSize = n
+		err = WriteEntryIndex("", meta, dir+"/"+ENTRY_INDEX_PATH)
+		if !errors.Is(err, ErrMalformedIndex) {
+			t.Errorf("Expected ErrMalformedIndex for a duplicated entry (runs of %d), got %v.", n, err)
+		}
+	}
+}
+
+func TestEntryIndexRuns(t *testing.T) {
+	dir := t.TempDir()
+	fai := dir + "/" + FASTA_INDEX_PATH
+	meta := dir + "/" + META_INDEX_PATH
+	index := dir + "/" + ENTRY_INDEX_PATH
+
+	// Runs of 3 records: the sequence and the metadata of an entry are
+	// found in different runs
+	ids := []string{"P7", "P3", "P9", "P1", "P5", "P8", "P2", "P6", "P4"}
+	var fl, ml string
+	for i, id := range ids {
+		fl += fmt.Sprintf("%s\t%d\t%d\t60\t61\n", id, i+1, 100*i)
+		ml += fmt.Sprintf("%s\t%d\t%d\n", ids[len(ids)-1-i], 10*i, i+1)
+	}
+	err := ioutil.WriteFile(fai, []byte(fl), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(meta, []byte(ml), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(n int) { entryRunSize = n }(entryRunSize)
+	entryRunSize = 3
+	err = WriteEntryIndex(fai, meta, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The temporary runs are removed
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("Expected the temporary runs to be removed, found %d files.", len(files))
+	}
+
+	ei, err := openEntryIndex(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ei.Close()
+	if ei.count != len(ids) {
+		t.Errorf("Expected %d records, got %d.", len(ids), ei.count)
+	}
+	for i, id := range ids {
+		el, ok, err := ei.find(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || el.seq.length != i+1 || el.seq.offset != int64(100*i) || el.meta.length != len(ids)-i || el.meta.offset != int64(10*(len(ids)-1-i)) {
+			t.Errorf("Unexpected location of %s: %v, %+v.", id, ok, el)
+		}
+	}
+
+	// The records are sorted
+	prev := ""
+	for i := 0; i < ei.count; i++ {
+		rec := make([]byte, ei.size)
+		_, err = ei.file.ReadAt(rec, int64(ENTRY_HEADER_SIZE+i*ei.size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := strings.TrimRight(string(rec[:ei.width]), "\x00")
+		if id <= prev {
+			t.Errorf("Unsorted record %s after %s.", id, prev)
+		}
+		prev = id
+	}
+}
@@ -0,0 +1,204 @@
+package refdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hdevillers/go-fannot/swiss"
+)
+
+const (
+	META_PATH       string = "metadata.jsonl"
+	META_INDEX_PATH string = "metadata.idx"
+)
+
+// Errors returned when reading the metadata of the entries
+var (
+	ErrMalformedDesc = errors.New("malformed hit description")
+	ErrMetaNotFound  = errors.New("entry missing from the metadata store")
+	ErrMalformedMeta = errors.New("malformed metadata store")
+)
+
+// Gene Ontology term of a reference entry
+type GoTerm struct {
+	Id       string `json:"id"`
+	Aspect   string `json:"aspect"`
+	Evidence string `json:"evidence"`
+	Term     string `json:"term"`
+}
+
+// Metadata of a reference entry (one JSON line of the metadata store)
+type Meta struct {
+	Access   string   `json:"access"`
+	Desc     string   `json:"desc"`
+	Name     string   `json:"name,omitempty"`
+	Locus    string   `json:"locus,omitempty"`
+	Organism string   `json:"organism"`
+	Function string   `json:"function,omitempty"`
+	Lineage  []string `json:"lineage,omitempty"`
+	Evidence int      `json:"evidence,omitempty"`
+	Reviewed bool     `json:"reviewed"` // Swiss-Prot (reviewed) or TrEMBL entry
+	DescType string   `json:"desc_type,omitempty"`
+	GoTerms  []GoTerm `json:"go_terms,omitempty"`
+	EC       []string `json:"ec,omitempty"`
+	Pathways []string `json:"pathways,omitempty"`
+}
+
+// Build the metadata of a parsed UniProt entry
+func NewMeta(e *swiss.Entry) *Meta {
+	m := Meta{
+		Access:   e.Access,
+		Desc:     e.Desc,
+		Name:     e.Name,
+		Locus:    e.Locus,
+		Organism: e.Organism,
+		Function: e.Function,
+		Lineage:  e.Lineage,
+		Reviewed: e.Reviewed,
+		DescType: e.DescType,
+		EC:       e.ECNumbers(),
+		Pathways: e.Pathways(),
+	}
+	m.Evidence, _ = strconv.Atoi(e.Evidence)
+	for _, t := range e.GoTerms() {
+		m.GoTerms = append(m.GoTerms, GoTerm{Id: t.Id, Aspect: t.Aspect, Evidence: t.Evidence, Term: t.Term})
+	}
+	return &m
+}
+
+// Parse the metadata from a FASTA description written by older versions
+// (Desc::Name::Locus::Organism::Function)
+func ParseDesc(access, desc string) (*Meta, error) {
+	values := strings.Split(desc, "::")
+	if len(values) != 5 {
+		return nil, fmt.Errorf("Unexpected description of the entry %s (%s): %w", access, desc, ErrMalformedDesc)
+	}
+
+	return &Meta{
+		Access:   access,
+		Desc:     values[0],
+		Name:     values[1],
+		Locus:    values[2],
+		Organism: values[3],
+		Function: values[4],
+	}, nil
+}
+
+// Writer of the metadata store: one JSON line per entry and an index
+// of the line offsets (accession, offset and length, tab separated)
+type MetaWriter struct {
+	data   *os.File
+	index  *os.File
+	dw     *bufio.Writer
+	iw     *bufio.Writer
+	offset int64
+}
+
+func NewMetaWriter(file, index string) (*MetaWriter, error) {
+	d, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	i, err := os.Create(index)
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	return &MetaWriter{data: d, index: i, dw: bufio.NewWriter(d), iw: bufio.NewWriter(i)}, nil
+}
+
+func (mw *MetaWriter) Write(m *Meta) error {
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	_, err = mw.dw.Write(line)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(mw.iw, "%s\t%d\t%d\n", m.Access, mw.offset, len(line))
+	if err != nil {
+		return err
+	}
+	mw.offset += int64(len(line))
+	return nil
+}
+
+func (mw *MetaWriter) Flush() error {
+	err := mw.dw.Flush()
+	if err != nil {
+		return err
+	}
+	return mw.iw.Flush()
+}
+
+func (mw *MetaWriter) Close() error {
+	err := mw.data.Close()
+	if cerr := mw.index.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Location of an entry in the metadata store
+type metaOffset struct {
+	offset int64
+	length int
+}
+
+// Random access reader of the metadata store (safe for concurrent use)
+type MetaStore struct {
+	file  *os.File
+	index *entryIndex
+}
+
+// Open the metadata store and the entry index of the DB
+func (r *Refdb) OpenMeta() (*MetaStore, error) {
+	ei, err := openEntryIndex(r.EntryIndex)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(r.Metadata)
+	if err != nil {
+		ei.Close()
+		return nil, err
+	}
+	return &MetaStore{file: f, index: ei}, nil
+}
+
+// Read the metadata of an entry
+func (ms *MetaStore) Get(access string) (*Meta, error) {
+	el, ok, err := ms.index.find(access)
+	if err != nil {
+		return nil, err
+	}
+	mo := el.meta
+	if !ok || mo.length == 0 {
+		return nil, fmt.Errorf("Failed to find the entry %s: %w", access, ErrMetaNotFound)
+	}
+	buf := make([]byte, mo.length)
+	_, err = ms.file.ReadAt(buf, mo.offset)
+	if err != nil {
+		return nil, err
+	}
+	var m Meta
+	err = json.Unmarshal(buf, &m)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode the metadata of the entry %s (%s): %w", access, err, ErrMalformedMeta)
+	}
+	return &m, nil
+}
+
+func (ms *MetaStore) Close() error {
+	err := ms.file.Close()
+	if cerr := ms.index.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
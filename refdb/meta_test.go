@@ -0,0 +1,92 @@
+package refdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hdevillers/go-fannot/swiss"
+)
+
+func TestMetaStore(t *testing.T) {
+	dir := t.TempDir()
+	r := Refdb{Metadata: dir + "/" + META_PATH, MetaIndex: dir + "/" + META_INDEX_PATH, EntryIndex: dir + "/" + ENTRY_INDEX_PATH}
+
+	// Write the metadata of the test entries
+	swr, err := swiss.NewReader("../swiss/testdata/swissprot.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer swr.Close()
+	mw, err := NewMetaWriter(r.Metadata, r.MetaIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mw.Close()
+	for swr.Next() {
+		e, err := swr.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = mw.Write(NewMeta(e))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = mw.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteEntryIndex("", r.MetaIndex, r.EntryIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms, err := r.OpenMeta()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+
+	// Random access, in any order
+	m, err := ms.Get("P0A6P9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "eno" || m.Evidence != 1 || !m.Reviewed || len(m.EC) != 1 || m.EC[0] != "4.2.1.11" || m.Lineage[0] != "Bacteria" {
+		t.Errorf("Unexpected metadata of P0A6P9: %+v.", m)
+	}
+	m, err = ms.Get("P00044")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "CYC1" || m.DescType != swiss.DESC_RECNAME {
+		t.Errorf("Unexpected metadata of P00044: %+v.", m)
+	}
+
+	_, err = ms.Get("P99999")
+	if !errors.Is(err, ErrMetaNotFound) {
+		t.Errorf("Expected ErrMetaNotFound for a missing entry, got %v.", err)
+	}
+}
+
+func TestParseDesc(t *testing.T) {
+	// Descriptions written by older versions
+	m, err := ParseDesc("P1", "Protein kinase A::PKA1::::Saccharomyces cerevisiae::")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Desc != "Protein kinase A" || m.Name != "PKA1" || m.Organism != "Saccharomyces cerevisiae" || m.Function != "" {
+		t.Errorf("Unexpected metadata: %+v.", m)
+	}
+
+	// Other layouts are rejected
+	for _, d := range []string{
+		"Protein kinase A",
+		"Enolase::eno::b2779::Escherichia coli.::::Bacteria; Proteobacteria.::1",
+	} {
+		_, err = ParseDesc("P2", d)
+		if !errors.Is(err, ErrMalformedDesc) {
+			t.Errorf("Expected ErrMalformedDesc for %s, got %v.", d, err)
+		}
+	}
+}
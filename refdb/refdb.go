@@ -3,52 +3,77 @@ package refdb
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/hdevillers/go-fannot/swiss"
 	"github.com/hdevillers/go-seq/seq"
-	"github.com/hdevillers/go-seq/seqio"
+	"github.com/hdevillers/go-seq/seqio/fasta"
 )
 
 const (
-	FASTA_PATH   string = "protein.fasta"
-	BLASTDB_PATH string = "blastdb"
-	JSON_PATH    string = "config.json"
+	FASTA_PATH     string = "protein.fasta"
+	BLASTDB_PATH   string = "blastdb"
+	DIAMONDDB_PATH string = "diamonddb.dmnd"
+	MMSEQSDB_PATH  string = "mmseqsdb"
+	JSON_PATH      string = "config.json"
+)
+
+// Errors returned when creating or finding a refdb
+var (
+	ErrRefDBNotFound  = errors.New("reference DB not found")
+	ErrRefDBExists    = errors.New("reference DB already exists")
+	ErrSourceNotFound = errors.New("source data file not found")
+)
+
+// Available search backends
+const (
+	BLAST_BACKEND   string = "blast"
+	DIAMOND_BACKEND string = "diamond"
+	MMSEQS_BACKEND  string = "mmseqs"
 )
 
 type Refdb struct {
-	Id        string
-	Desc      string
-	Root      string
-	Source    string
-	Blastdb   string
-	Fasta     string
-	Nprot     int
-	Equal     bool // Indicate if the DB contain proteins of the query
-	OverWrite bool // Indicate if annotations from the DB can overwrite "similar" annotations
-	Reviewed  bool // Indicate if the DB is reviewed (Uniprot) or not (TrEmbl)
-	GeneName  bool // Indicate if we can transfer gene name in the query feature
+	Id         string
+	Desc       string
+	Root       string
+	Source     string
+	Blastdb    string
+	Diamonddb  string
+	Mmseqsdb   string
+	Backends   []string // Search backends built for this DB
+	Fasta      string
+	FastaIndex string // Index of the sequences in the FASTA file (faidx)
+	Metadata   string // Metadata of the entries (JSON Lines, see Meta)
+	MetaIndex  string // Offsets of the entries in the metadata file
+	EntryIndex string // Sorted index of the entries (sequences and metadata)
+	Nprot      int
+	Equal      bool // Indicate if the DB contain proteins of the query
+	OverWrite  bool // Indicate if annotations from the DB can overwrite "similar" annotations
+	Reviewed   bool // Indicate if the DB is reviewed (Uniprot) or not (TrEmbl)
+	GeneName   bool // Indicate if we can transfer gene name in the query feature
 }
 
-func NewRefdb(outdir, id, source, desc string, equal bool, ow bool, re bool, gn bool) *Refdb {
+func NewRefdb(outdir, id, source, desc string, equal bool, ow bool, re bool, gn bool) (*Refdb, error) {
 	var rdb Refdb
 
 	// Check if the source exist
 	_, err := os.Stat(source)
 	if os.IsNotExist(err) {
-		panic("The input source data file does not exists.")
+		return nil, fmt.Errorf("The input source data file %s does not exist: %w", source, ErrSourceNotFound)
 	}
 
 	// Check if the output directory exists
 	_, err = os.Stat(outdir)
 	if os.IsNotExist(err) {
-		err = os.Mkdir(outdir, 770)
-		if err == nil {
-			panic(err)
+		err = os.Mkdir(outdir, 0770)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -56,7 +81,7 @@ func NewRefdb(outdir, id, source, desc string, equal bool, ow bool, re bool, gn
 	if !filepath.IsAbs(outdir) {
 		apath, err := filepath.Abs(outdir)
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
 		outdir = apath
 	}
@@ -65,9 +90,12 @@ func NewRefdb(outdir, id, source, desc string, equal bool, ow bool, re bool, gn
 	rootdir := outdir + "/" + id
 	_, err = os.Stat(rootdir)
 	if os.IsNotExist(err) {
-		os.Mkdir(rootdir, 0770)
+		err = os.Mkdir(rootdir, 0770)
+		if err != nil {
+			return nil, err
+		}
 	} else {
-		panic("The refdb name is already used in the output directory.")
+		return nil, fmt.Errorf("The refdb name %s is already used in the output directory: %w", id, ErrRefDBExists)
 	}
 
 	// Setup path values
@@ -79,63 +107,182 @@ func NewRefdb(outdir, id, source, desc string, equal bool, ow bool, re bool, gn
 	rdb.OverWrite = ow
 	rdb.Reviewed = re
 	rdb.GeneName = gn
+	rdb.Backends = []string{BLAST_BACKEND}
 
-	return &rdb
+	return &rdb, nil
+}
+
+// Check that a search backend name is supported
+func IsBackend(b string) bool {
+	return b == BLAST_BACKEND || b == DIAMOND_BACKEND || b == MMSEQS_BACKEND
+}
+
+// Indicate if a search backend has been built for the DB
+func (r *Refdb) HasBackend(b string) bool {
+	// DBs created before the introduction of backends only have BLAST
+	if len(r.Backends) == 0 {
+		return b == BLAST_BACKEND && r.Blastdb != ""
+	}
+	for _, rb := range r.Backends {
+		if rb == b {
+			return true
+		}
+	}
+	return false
 }
 
-func (r *Refdb) LoadSource() {
+func (r *Refdb) LoadSource() error {
 	// Init. the swissprot reader
-	swr := swiss.NewReader(r.Source)
-	swr.PanicOnError()
+	swr, err := swiss.NewReader(r.Source)
+	if err != nil {
+		return err
+	}
 	defer swr.Close()
 
 	// Init. the fasta writer
 	r.Fasta = r.Root + "/" + FASTA_PATH
-	fw := seqio.NewWriter(r.Fasta, "fasta", false)
+	f, err := os.Create(r.Fasta)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fw := fasta.NewWriter(bufio.NewWriter(f))
+
+	// Init. the metadata store
+	r.Metadata = r.Root + "/" + META_PATH
+	r.MetaIndex = r.Root + "/" + META_INDEX_PATH
+	mw, err := NewMetaWriter(r.Metadata, r.MetaIndex)
+	if err != nil {
+		return err
+	}
+	defer mw.Close()
 
 	// Scan entries
 	ne := 0
 	for swr.Next() {
-		e := swr.Parse()
-		swr.PanicOnError()
+		e, err := swr.Parse()
+		if err != nil {
+			return err
+		}
 		ne++
 
-		desc := e.Desc + "::" + e.Name + "::" + e.Locus + "::" + e.Organism + "::" + e.Function
+		// The metadata are stored apart from the sequences
+		err = mw.Write(NewMeta(e))
+		if err != nil {
+			return err
+		}
 		nseq := seq.NewSeq(e.Access)
-		nseq.Desc = desc
+		nseq.Desc = e.Desc
 		nseq.Sequence = []byte(e.Sequence)
 
-		fw.Write(*nseq)
+		err = fw.Write(*nseq)
+		if err != nil {
+			return err
+		}
+	}
+	if swr.Err() != nil {
+		return swr.Err()
+	}
+	err = fw.Flush()
+	if err != nil {
+		return err
+	}
+	err = mw.Flush()
+	if err != nil {
+		return err
 	}
 	r.Nprot = ne
 
-	// Prepare the BLASTDB
+	// Index the sequences and the metadata for random access
+	r.FastaIndex = r.Root + "/" + FASTA_INDEX_PATH
+	err = IndexFasta(r.Fasta, r.FastaIndex)
+	if err != nil {
+		return err
+	}
+	r.EntryIndex = r.Root + "/" + ENTRY_INDEX_PATH
+	err = WriteEntryIndex(r.FastaIndex, r.MetaIndex, r.EntryIndex)
+	if err != nil {
+		return err
+	}
+
+	// Prepare the search DBs
+	for _, b := range r.Backends {
+		switch b {
+		case BLAST_BACKEND:
+			err = r.MakeBlastDB()
+		case DIAMOND_BACKEND:
+			err = r.MakeDiamondDB()
+		case MMSEQS_BACKEND:
+			err = r.MakeMmseqsDB()
+		default:
+			err = fmt.Errorf("Unknown search backend: %s.", b)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run a DB building command and report its error output on failure
+func runBuildCmd(cmd *exec.Cmd) error {
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w\n%s", cmd.Args[0], err, out)
+	}
+	return nil
+}
+
+// Build the BLAST DB from the FASTA file
+func (r *Refdb) MakeBlastDB() error {
 	r.Blastdb = r.Root + "/" + BLASTDB_PATH
-	err := exec.Command("makeblastdb",
+	return runBuildCmd(exec.Command("makeblastdb",
 		"-in", r.Fasta,
 		"-out", r.Blastdb,
 		"-dbtype", "prot",
 		"-input_type", "fasta",
-	).Run()
-	if err != nil {
-		panic(err)
-	}
+	))
+}
+
+// Build the DIAMOND DB from the FASTA file
+func (r *Refdb) MakeDiamondDB() error {
+	r.Diamonddb = r.Root + "/" + DIAMONDDB_PATH
+	return runBuildCmd(exec.Command("diamond", "makedb",
+		"--in", r.Fasta,
+		"--db", r.Diamonddb,
+		"--quiet",
+	))
+}
+
+// Build the MMseqs2 target DB from the FASTA file
+func (r *Refdb) MakeMmseqsDB() error {
+	r.Mmseqsdb = r.Root + "/" + MMSEQSDB_PATH
+	return runBuildCmd(exec.Command("mmseqs", "createdb",
+		r.Fasta,
+		r.Mmseqsdb,
+		"-v", "1",
+	))
 }
 
 func (r *Refdb) PrintInfoHeader() {
-	fmt.Println("ID\t#Proteins\tDescription")
+	fmt.Println("ID\t#Proteins\tBackends\tDescription")
 }
 
 func (r *Refdb) PrintInfo() {
-	fmt.Printf("%s\t%d\t%s\n", r.Id, r.Nprot, r.Desc)
+	backends := r.Backends
+	if len(backends) == 0 && r.Blastdb != "" {
+		backends = []string{BLAST_BACKEND}
+	}
+	fmt.Printf("%s\t%d\t%s\t%s\n", r.Id, r.Nprot, strings.Join(backends, ","), r.Desc)
 }
 
 // Create a json file from an existing object
-func (r *Refdb) WriteJson() {
+func (r *Refdb) WriteJson() error {
 	// Create the output file
 	f, err := os.Create(r.Root + "/" + JSON_PATH)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer f.Close()
 
@@ -148,20 +295,20 @@ func (r *Refdb) WriteJson() {
 	// encode
 	err = jw.Encode(r)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	fw.Flush()
+	return fw.Flush()
 }
 
 // create a Refdb object from a json file
-func ReadJson(file string) *Refdb {
+func ReadJson(file string) (*Refdb, error) {
 	var refdb Refdb
 
 	// Open the file
 	f, err := os.Open(file)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	defer f.Close()
 
@@ -174,14 +321,14 @@ func ReadJson(file string) *Refdb {
 	// Decode the entry
 	err = jr.Decode(&refdb)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("Failed to decode the refdb configuration %s: %w", file, err)
 	}
 
-	return &refdb
+	return &refdb, nil
 }
 
 // Create a Refdb object from an id and a directory
-func FindRefDB(id, dir string) *Refdb {
+func FindRefDB(id, dir string) (*Refdb, error) {
 	// Check if the provided id is a JSON file
 	tjson := regexp.MustCompile(`\.json$`)
 	if tjson.MatchString(id) {
@@ -190,7 +337,7 @@ func FindRefDB(id, dir string) *Refdb {
 		if os.IsNotExist(err) {
 			_, err := os.Stat(dir + "/" + id)
 			if os.IsNotExist(err) {
-				panic(fmt.Sprintf("Failed to find the JSON file (%s/)%s.", dir, id))
+				return nil, fmt.Errorf("Failed to find the JSON file (%s/)%s: %w", dir, id, ErrRefDBNotFound)
 			} else {
 				json = dir + "/" + id
 			}
@@ -205,7 +352,7 @@ func FindRefDB(id, dir string) *Refdb {
 			json = id + "/" + JSON_PATH
 			_, err := os.Stat(json)
 			if os.IsNotExist(err) {
-				panic(fmt.Sprintf("Failed to find the DB with ID: %s (directory: %s).", id, dir))
+				return nil, fmt.Errorf("Failed to find the DB with ID %s (directory: %s): %w", id, dir, ErrRefDBNotFound)
 			}
 		}
 		return ReadJson(json)
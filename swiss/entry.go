@@ -3,67 +3,258 @@ package swiss
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
+// Types of the name used as description of an entry
+const (
+	DESC_RECNAME string = "RecName" // Recommended name (Swiss-Prot)
+	DESC_SUBNAME string = "SubName" // Submitted name (TrEMBL)
+)
+
+// Name of a protein (DE RecName, AltName or SubName). Type is the kind
+// of alternative names without full name (Allergen, Biotech, CD_antigen
+// or INN, Full is then the name).
+type ProteinName struct {
+	Full  string
+	Short []string
+	EC    []string
+	Type  string
+}
+
+// Names of a protein or of a protein component (DE Includes/Contains)
+type ProteinDesc struct {
+	RecName  ProteinName
+	AltNames []ProteinName
+	SubNames []ProteinName
+}
+
+// Gene of the entry (GN)
+type Gene struct {
+	Name         string
+	Synonyms     []string
+	OrderedLocus []string
+	ORFNames     []string
+}
+
+// Host organism of a virus (OH)
+type Host struct {
+	TaxonId string
+	Name    string
+}
+
+// Reference of the entry (RN, RP, RC, RX, RG, RA, RT, RL)
+type Reference struct {
+	Number    int
+	Position  string
+	Comment   string
+	CrossRefs string
+	Group     string
+	Authors   string
+	Title     string
+	Location  string
+}
+
+// Comment block (CC -!- TOPIC: text)
+type Comment struct {
+	Topic string
+	Text  string
+}
+
+// Cross-reference to another database (DR), Extra contains the
+// additional identifiers and Isoform the isoform of the entry (if any)
+type CrossRef struct {
+	Db      string
+	Id      string
+	Extra   []string
+	Isoform string
+}
+
+// Qualifier of a feature (FT /name="value")
+type Qualifier struct {
+	Name  string
+	Value string
+}
+
+// Feature of the sequence (FT), positions are 0 when unknown
+type Feature struct {
+	Key        string
+	Location   string
+	Start      int
+	End        int
+	Qualifiers []Qualifier
+}
+
+// Gene Ontology annotation (DR GO line)
+type GoTerm struct {
+	Id       string // GO identifier (GO:XXXXXXX)
+	Aspect   string // C (component), F (function) or P (process)
+	Term     string
+	Evidence string // Evidence code (ex. IDA)
+	Source   string // Source of the annotation (ex. UniProtKB)
+}
+
 type Entry struct {
-	Access   string
-	Name     string
-	Locus    string
-	Desc     string
-	Function string
-	Length   int
-	Organism string
-	Phylum   string
-	Sequence string
-	Evidence string
+	Access     string
+	Name       string
+	Locus      string
+	Desc       string
+	DescType   string // Type of the name used as description (RecName or SubName)
+	Function   string
+	Length     int
+	Organism   string
+	Phylum     string
+	Sequence   string
+	Evidence   string
+	EntryName  string   // Entry name (ID)
+	Reviewed   bool     // Swiss-Prot (reviewed) or TrEMBL (unreviewed) entry
+	Accessions []string // All accessions, the first one is the primary accession
+	Dates      []string // DT lines
+	Protein    ProteinDesc
+	Includes   []ProteinDesc // Domains of the protein
+	Contains   []ProteinDesc // Chains of the protein precursor
+	Flags      []string      // DE Flags (Fragment, Fragments or Precursor)
+	Genes      []Gene
+	Organelle  string   // OG
+	Lineage    []string // OC
+	TaxonId    string   // OX NCBI_TaxID
+	Hosts      []Host
+	References []Reference
+	Comments   []Comment
+	CrossRefs  []CrossRef
+	Keywords   []string
+	Features   []Feature
+	MolWeight  int    // SQ molecular weight (Da)
+	Crc64      string // SQ CRC64 checksum
 }
 
 func (e *Entry) Info() {
 	fmt.Printf("Accession:\t%s\n", e.Access)
+	fmt.Printf("Entry name:\t%s\n", e.EntryName)
+	fmt.Printf("Reviewed:\t%t\n", e.Reviewed)
 	fmt.Printf("Gene name:\t%s\n", e.Name)
 	fmt.Printf("Locus tag:\t%s\n", e.Locus)
-	fmt.Printf("Description:\t%s\n", e.Desc)
+	fmt.Printf("Description:\t%s (%s)\n", e.Desc, e.DescType)
 	fmt.Printf("Function:\t%s\n", e.Function)
 	fmt.Printf("Length (aa):\t%d\n", e.Length)
 	fmt.Printf("Organism:\t%s\n", e.Organism)
 	fmt.Printf("Phylum: \t%s\n", e.Phylum)
+	fmt.Printf("Taxon ID:\t%s\n", e.TaxonId)
 	fmt.Printf("Evidence:\t%s\n", e.Evidence)
 	fmt.Printf("Sequence:\t%s\n", e.Sequence)
 }
 
-func (e *Entry) TestEvidence(re string) bool {
+func (e *Entry) TestEvidence(re string) (bool, error) {
 	retest, err := regexp.Compile(re)
 	if err != nil {
-		panic("[TestEvidence]: Cannot compile regex.")
+		return false, fmt.Errorf("[TestEvidence]: Cannot compile regex: %w", err)
 	}
 
 	if retest.MatchString(e.Evidence) {
-		return true
+		return true, nil
 	}
 
-	return false
+	return false, nil
 }
 
-func (e *Entry) TestTaxonomy(re string) bool {
+func (e *Entry) TestTaxonomy(re string) (bool, error) {
 	retest, err := regexp.Compile(re)
 	if err != nil {
-		panic("[TestTaxonomy]: Cannot compile regex.")
+		return false, fmt.Errorf("[TestTaxonomy]: Cannot compile regex: %w", err)
 	}
 
 	// First test the Organism string
 	if retest.MatchString(e.Organism) {
-		return true
+		return true, nil
 	}
 
 	// Then test the phylum (if previous test is false)
 	if retest.MatchString(e.Phylum) {
-		return true
+		return true, nil
 	}
 
-	return false
+	return false, nil
 }
 
 func (e *Entry) Test(tk, ts, ek, es string) bool {
 	// Skip direct
 	return false
 }
+
+// Return the texts of the comments of a topic (e.g. FUNCTION)
+func (e *Entry) GetComments(topic string) []string {
+	texts := make([]string, 0)
+	for _, c := range e.Comments {
+		if c.Topic == topic {
+			texts = append(texts, c.Text)
+		}
+	}
+	return texts
+}
+
+// Return the cross-references to a database (e.g. GO)
+func (e *Entry) GetCrossRefs(db string) []CrossRef {
+	refs := make([]CrossRef, 0)
+	for _, r := range e.CrossRefs {
+		if r.Db == db {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// Return the Gene Ontology annotations of the entry
+func (e *Entry) GoTerms() []GoTerm {
+	terms := make([]GoTerm, 0)
+	for _, r := range e.GetCrossRefs("GO") {
+		gt := GoTerm{Id: r.Id}
+		if len(r.Extra) > 0 {
+			at := strings.SplitN(r.Extra[0], ":", 2)
+			gt.Aspect = at[0]
+			if len(at) == 2 {
+				gt.Term = at[1]
+			}
+		}
+		if len(r.Extra) > 1 {
+			es := strings.SplitN(r.Extra[1], ":", 2)
+			gt.Evidence = es[0]
+			if len(es) == 2 {
+				gt.Source = es[1]
+			}
+		}
+		terms = append(terms, gt)
+	}
+	return terms
+}
+
+// Return the EC numbers of the protein (all names, without duplicate)
+func (e *Entry) ECNumbers() []string {
+	ec := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(n ProteinName) {
+		for _, x := range n.EC {
+			if !seen[x] {
+				seen[x] = true
+				ec = append(ec, x)
+			}
+		}
+	}
+	add(e.Protein.RecName)
+	for _, n := range e.Protein.AltNames {
+		add(n)
+	}
+	for _, n := range e.Protein.SubNames {
+		add(n)
+	}
+	return ec
+}
+
+// Return the pathways of the protein (CC PATHWAY, without evidence tags
+// and final point)
+func (e *Entry) Pathways() []string {
+	pw := make([]string, 0)
+	for _, c := range e.GetComments("PATHWAY") {
+		pw = append(pw, strings.TrimSuffix(stripEvidence(c), "."))
+	}
+	return pw
+}
@@ -0,0 +1,455 @@
+package swiss
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Evidence tags (ex. {ECO:0000269|PubMed:123})
+var reEvidence = regexp.MustCompile(`\s*\{[^}]*\}`)
+
+// Remove the evidence tags of a value
+func stripEvidence(s string) string {
+	return strings.TrimSpace(reEvidence.ReplaceAllString(s, ""))
+}
+
+// Split a list of values (separated by sep) and remove empty values,
+// evidence tags and the final point
+func splitValues(s, sep string) []string {
+	s = strings.TrimSuffix(strings.TrimSpace(stripEvidence(s)), ".")
+	values := make([]string, 0)
+	for _, v := range strings.Split(s, sep) {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Lines of an entry grouped by line type (the content starts at the
+// sixth column), reference lines are grouped by reference
+type entryLines struct {
+	lines map[string][]string
+	refs  []map[string][]string
+}
+
+func splitEntryLines(data []string) *entryLines {
+	el := entryLines{lines: make(map[string][]string)}
+	for _, line := range data {
+		if len(line) < 2 {
+			continue
+		}
+		key := line[0:2]
+		content := ""
+		if len(line) > 5 {
+			content = line[5:]
+		}
+		if key[0] == 'R' {
+			if key == "RN" || len(el.refs) == 0 {
+				el.refs = append(el.refs, make(map[string][]string))
+			}
+			ref := el.refs[len(el.refs)-1]
+			ref[key] = append(ref[key], content)
+			continue
+		}
+		el.lines[key] = append(el.lines[key], content)
+	}
+	return &el
+}
+
+// Append a continuation line to a text (words split at a hyphen are
+// joined without space)
+func appendLine(text, line string) string {
+	line = strings.TrimSpace(line)
+	if text == "" || strings.HasSuffix(text, "-") {
+		return text + line
+	}
+	if line == "" {
+		return text
+	}
+	return text + " " + line
+}
+
+// Join the lines of a type
+func joinLines(lines []string) string {
+	text := ""
+	for _, l := range lines {
+		text = appendLine(text, l)
+	}
+	return text
+}
+
+// Parse all the lines of an entry
+func parseEntry(data []string) (*Entry, error) {
+	var entry Entry
+	var err error
+	el := splitEntryLines(data)
+
+	// Identification
+	if len(el.lines["ID"]) == 0 {
+		return nil, fmt.Errorf("Missing identification (ID) line: %w", ErrMalformedEntry)
+	}
+	id := el.lines["ID"][0]
+	entry.Length, err = parseLength(id)
+	if err != nil {
+		return nil, err
+	}
+	idf := strings.Fields(id)
+	entry.EntryName = idf[0]
+	entry.Reviewed = len(idf) > 1 && idf[1] == "Reviewed;"
+
+	// Accessions
+	entry.Accessions = splitValues(joinLines(el.lines["AC"]), ";")
+	if len(entry.Accessions) == 0 {
+		return nil, fmt.Errorf("Missing accession (AC) line (%s): %w", entry.EntryName, ErrMalformedEntry)
+	}
+	entry.Access = entry.Accessions[0]
+
+	for _, dt := range el.lines["DT"] {
+		entry.Dates = append(entry.Dates, strings.TrimSpace(dt))
+	}
+
+	// Description: recommended name or, in TrEMBL entries, first
+	// submitted name
+	parseDescription(&entry, el.lines["DE"])
+	if entry.Protein.RecName.Full != "" {
+		entry.Desc = entry.Protein.RecName.Full
+		entry.DescType = DESC_RECNAME
+	} else if len(entry.Protein.SubNames) > 0 && entry.Protein.SubNames[0].Full != "" {
+		entry.Desc = entry.Protein.SubNames[0].Full
+		entry.DescType = DESC_SUBNAME
+	}
+
+	parseGenes(&entry, el.lines["GN"])
+	if len(entry.Genes) > 0 {
+		entry.Name = entry.Genes[0].Name
+		if len(entry.Genes[0].OrderedLocus) > 0 {
+			entry.Locus = entry.Genes[0].OrderedLocus[0]
+		}
+	}
+
+	// Organism
+	entry.Organism = joinLines(el.lines["OS"])
+	entry.Organelle = strings.TrimSuffix(joinLines(el.lines["OG"]), ".")
+	entry.Phylum = joinLines(el.lines["OC"])
+	entry.Lineage = splitValues(entry.Phylum, ";")
+	for _, ox := range splitValues(joinLines(el.lines["OX"]), ";") {
+		if strings.HasPrefix(ox, "NCBI_TaxID=") {
+			entry.TaxonId = strings.TrimPrefix(ox, "NCBI_TaxID=")
+		}
+	}
+	for _, oh := range el.lines["OH"] {
+		h := strings.SplitN(strings.TrimSuffix(strings.TrimSpace(oh), "."), ";", 2)
+		host := Host{TaxonId: strings.TrimPrefix(h[0], "NCBI_TaxID=")}
+		if len(h) == 2 {
+			host.Name = strings.TrimSpace(h[1])
+		}
+		entry.Hosts = append(entry.Hosts, host)
+	}
+
+	// References
+	for _, ref := range el.refs {
+		r, err := parseReference(ref)
+		if err != nil {
+			return nil, fmt.Errorf("%w (%s)", err, entry.EntryName)
+		}
+		entry.References = append(entry.References, *r)
+	}
+
+	// Comments
+	parseComments(&entry, el.lines["CC"])
+	fn := entry.GetComments("FUNCTION")
+	if len(fn) > 0 && len(fn[0]) > 0 {
+		entry.Function = commentFunctionCleanup("FUNCTION: " + fn[0])
+	}
+
+	// Cross-references
+	for _, dr := range el.lines["DR"] {
+		entry.CrossRefs = append(entry.CrossRefs, parseCrossRef(dr))
+	}
+
+	// Protein existence
+	entry.Evidence, err = parseEvidence(joinLines(el.lines["PE"]))
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Keywords = splitValues(joinLines(el.lines["KW"]), ";")
+
+	err = parseFeatures(&entry, el.lines["FT"])
+	if err != nil {
+		return nil, err
+	}
+
+	// Sequence
+	err = parseSequenceHeader(&entry, joinLines(el.lines["SQ"]))
+	if err != nil {
+		return nil, err
+	}
+	entry.Sequence = strings.Join(strings.Fields(strings.Join(el.lines["  "], "")), "")
+
+	return &entry, nil
+}
+
+// Parse the DE lines: names of the protein and of its components
+func parseDescription(entry *Entry, lines []string) {
+	desc := &entry.Protein
+	var name *ProteinName
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "Includes:":
+			entry.Includes = append(entry.Includes, ProteinDesc{})
+			desc = &entry.Includes[len(entry.Includes)-1]
+			name = nil
+			continue
+		case line == "Contains:":
+			entry.Contains = append(entry.Contains, ProteinDesc{})
+			desc = &entry.Contains[len(entry.Contains)-1]
+			name = nil
+			continue
+		case strings.HasPrefix(line, "Flags:"):
+			entry.Flags = append(entry.Flags, splitValues(line[6:], ";")...)
+			continue
+		case strings.HasPrefix(line, "RecName:"):
+			name = &desc.RecName
+			line = line[8:]
+		case strings.HasPrefix(line, "AltName:"):
+			desc.AltNames = append(desc.AltNames, ProteinName{})
+			name = &desc.AltNames[len(desc.AltNames)-1]
+			line = line[8:]
+		case strings.HasPrefix(line, "SubName:"):
+			desc.SubNames = append(desc.SubNames, ProteinName{})
+			name = &desc.SubNames[len(desc.SubNames)-1]
+			line = line[8:]
+		}
+		if name == nil {
+			continue
+		}
+
+		// Name values (Key=Value;)
+		kv := strings.SplitN(stripEvidence(strings.TrimSuffix(strings.TrimSpace(line), ";")), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "Full":
+			name.Full = kv[1]
+		case "Short":
+			name.Short = append(name.Short, kv[1])
+		case "EC":
+			name.EC = append(name.EC, kv[1])
+		default:
+			name.Type = kv[0]
+			name.Full = kv[1]
+		}
+	}
+}
+
+// Parse the GN lines (genes are separated by "and" lines)
+func parseGenes(entry *Entry, lines []string) {
+	blocks := make([]string, 0)
+	cur := make([]string, 0)
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "and" {
+			blocks = append(blocks, joinLines(cur))
+			cur = cur[:0]
+			continue
+		}
+		cur = append(cur, line)
+	}
+	if len(cur) > 0 {
+		blocks = append(blocks, joinLines(cur))
+	}
+
+	for _, b := range blocks {
+		var g Gene
+		for _, item := range strings.Split(stripEvidence(b), ";") {
+			kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch kv[0] {
+			case "Name":
+				g.Name = strings.TrimSpace(kv[1])
+			case "Synonyms":
+				g.Synonyms = splitValues(kv[1], ",")
+			case "OrderedLocusNames":
+				g.OrderedLocus = splitValues(kv[1], ",")
+			case "ORFNames":
+				g.ORFNames = splitValues(kv[1], ",")
+			}
+		}
+		entry.Genes = append(entry.Genes, g)
+	}
+}
+
+// Parse the lines of a reference
+func parseReference(ref map[string][]string) (*Reference, error) {
+	var r Reference
+	rn := strings.Trim(stripEvidence(joinLines(ref["RN"])), "[]")
+	n, err := strconv.Atoi(rn)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid reference number (%s): %w", rn, ErrMalformedEntry)
+	}
+	r.Number = n
+	if len(ref["RA"]) == 0 && len(ref["RG"]) == 0 {
+		return nil, fmt.Errorf("Missing reference authors (RA or RG) line [%d]: %w", n, ErrMalformedEntry)
+	}
+	if len(ref["RL"]) == 0 {
+		return nil, fmt.Errorf("Missing reference location (RL) line [%d]: %w", n, ErrMalformedEntry)
+	}
+	r.Position = strings.TrimSuffix(stripEvidence(joinLines(ref["RP"])), ".")
+	r.Comment = strings.TrimSuffix(stripEvidence(joinLines(ref["RC"])), ";")
+	r.CrossRefs = strings.TrimSuffix(joinLines(ref["RX"]), ";")
+	r.Group = strings.TrimSuffix(joinLines(ref["RG"]), ";")
+	r.Authors = strings.TrimSuffix(joinLines(ref["RA"]), ";")
+	r.Title = strings.Trim(strings.TrimSuffix(joinLines(ref["RT"]), ";"), `"`)
+	r.Location = strings.TrimSuffix(joinLines(ref["RL"]), ".")
+	return &r, nil
+}
+
+// Parse the CC lines into topics (the copyright block is skipped)
+func parseComments(entry *Entry, lines []string) {
+	var cur *Comment
+	text := ""
+	flush := func() {
+		if cur != nil {
+			cur.Text = text
+			entry.Comments = append(entry.Comments, *cur)
+		}
+		cur = nil
+		text = ""
+	}
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "-----") {
+			break
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "-!- ") {
+			flush()
+			tv := strings.SplitN(line[4:], ":", 2)
+			cur = &Comment{Topic: tv[0]}
+			if len(tv) == 2 {
+				text = strings.TrimSpace(tv[1])
+			}
+			continue
+		}
+		if cur != nil {
+			text = appendLine(text, line)
+		}
+	}
+	flush()
+}
+
+// Parse a DR line (DB; ID; extra; ...; extra. [isoform])
+func parseCrossRef(line string) CrossRef {
+	var cr CrossRef
+	line = strings.TrimSpace(line)
+	if i := strings.LastIndex(line, " ["); i >= 0 && strings.HasSuffix(line, "]") {
+		cr.Isoform = line[i+2 : len(line)-1]
+		line = line[:i]
+	}
+	values := strings.Split(strings.TrimSuffix(line, "."), ";")
+	for i, v := range values {
+		v = strings.TrimSpace(v)
+		switch i {
+		case 0:
+			cr.Db = v
+		case 1:
+			cr.Id = v
+		default:
+			cr.Extra = append(cr.Extra, v)
+		}
+	}
+	return cr
+}
+
+// Parse the FT lines: the key is in columns 6-21, the location and the
+// qualifiers start at column 22 (qualifier values may span several lines)
+func parseFeatures(entry *Entry, lines []string) error {
+	var f *Feature
+	for _, line := range lines {
+		if len(line) <= 16 {
+			continue
+		}
+		key := strings.TrimSpace(line[0:16])
+		value := strings.TrimSpace(line[16:])
+		if key != "" {
+			entry.Features = append(entry.Features, Feature{Key: key, Location: value})
+			f = &entry.Features[len(entry.Features)-1]
+			f.Start, f.End = parseLocation(value)
+			continue
+		}
+		if f == nil {
+			return fmt.Errorf("Feature qualifier without feature (%s): %w", line, ErrMalformedEntry)
+		}
+		if strings.HasPrefix(value, "/") {
+			nv := strings.SplitN(value[1:], "=", 2)
+			q := Qualifier{Name: nv[0]}
+			if len(nv) == 2 {
+				q.Value = nv[1]
+			}
+			f.Qualifiers = append(f.Qualifiers, q)
+		} else if len(f.Qualifiers) > 0 {
+			// Continuation of the previous qualifier value
+			q := &f.Qualifiers[len(f.Qualifiers)-1]
+			q.Value = appendLine(q.Value, value)
+		}
+	}
+
+	// Remove the quotes of the qualifier values
+	for i := range entry.Features {
+		for j := range entry.Features[i].Qualifiers {
+			q := &entry.Features[i].Qualifiers[j]
+			q.Value = strings.TrimSuffix(strings.TrimPrefix(q.Value, `"`), `"`)
+		}
+	}
+	return nil
+}
+
+// Retrieve the positions of a feature location (ex. 10..52, 12, <1..?)
+func parseLocation(loc string) (int, int) {
+	loc = loc[strings.LastIndex(loc, ":")+1:]
+	bounds := strings.SplitN(loc, "..", 2)
+	pos := func(s string) int {
+		p, err := strconv.Atoi(strings.TrimLeft(s, "<>"))
+		if err != nil {
+			return 0
+		}
+		return p
+	}
+	start := pos(bounds[0])
+	if len(bounds) == 1 {
+		return start, start
+	}
+	return start, pos(bounds[1])
+}
+
+// Parse the SQ line (SEQUENCE length AA; weight MW; checksum CRC64;)
+func parseSequenceHeader(entry *Entry, sq string) error {
+	if sq == "" {
+		return fmt.Errorf("Missing sequence header (SQ) line (%s): %w", entry.EntryName, ErrMalformedEntry)
+	}
+	for _, item := range strings.Split(sq, ";") {
+		f := strings.Fields(item)
+		if len(f) < 2 {
+			continue
+		}
+		switch f[len(f)-1] {
+		case "MW":
+			mw, err := strconv.Atoi(f[len(f)-2])
+			if err != nil {
+				return fmt.Errorf("Invalid molecular weight (%s): %w", sq, ErrMalformedEntry)
+			}
+			entry.MolWeight = mw
+		case "CRC64":
+			entry.Crc64 = f[len(f)-2]
+		}
+	}
+	return nil
+}
@@ -19,30 +19,18 @@ type Reader struct {
 	err     error
 	restart *regexp.Regexp
 	reend   *regexp.Regexp
-	reac    *regexp.Regexp
-	regn    *regexp.Regexp
-	relt    *regexp.Regexp
-	rede    *regexp.Regexp
-	rese    *regexp.Regexp
-	resc    *regexp.Regexp
 }
 
-func NewReader(file string) *Reader {
+func NewReader(file string) (*Reader, error) {
 	// Open the file
 	f, err := os.Open(file)
 	if err != nil {
-		return &Reader{err: err}
+		return nil, err
 	}
 
 	// Setup regex to detecte beginning and end of an entry
 	restart := regexp.MustCompile(`^ID   `)
 	reend := regexp.MustCompile(`^\/\/$`)
-	reac := regexp.MustCompile(`;\s?`)
-	regn := regexp.MustCompile(`Name=(\w+)`)
-	relt := regexp.MustCompile(`OrderedLocusNames=([\w\-]+)`)
-	rede := regexp.MustCompile(`^RecName\: Full=`)
-	rese := regexp.MustCompile(`\s`)
-	resc := regexp.MustCompile(`\;`)
 
 	// If the dat file has a 'gz' extention, then use zlib
 	var testGZ = regexp.MustCompile(`\.gz$`)
@@ -50,20 +38,15 @@ func NewReader(file string) *Reader {
 		// Use zlib
 		fgzip, err := gzip.NewReader(f)
 		if err != nil {
-			return &Reader{err: err}
+			f.Close()
+			return nil, err
 		}
 		return &Reader{
 			closer:  fgzip,
 			scanner: bufio.NewScanner(fgzip),
 			restart: restart,
 			reend:   reend,
-			reac:    reac,
-			regn:    regn,
-			relt:    relt,
-			rede:    rede,
-			rese:    rese,
-			resc:    resc,
-		}
+		}, nil
 	} else {
 		// Regular text file
 		return &Reader{
@@ -71,13 +54,7 @@ func NewReader(file string) *Reader {
 			scanner: bufio.NewScanner(f),
 			restart: restart,
 			reend:   reend,
-			reac:    reac,
-			regn:    regn,
-			relt:    relt,
-			rede:    rede,
-			rese:    rese,
-			resc:    resc,
-		}
+		}, nil
 	}
 }
 
@@ -85,43 +62,47 @@ func (r *Reader) Next() bool {
 	// Empty the current data
 	r.data = nil
 
-	// Scan a first line
-	test := r.scanner.Scan()
-	if !test {
-		// The file is probable EOF
-		return false
+	// Scan a first line (skip empty lines between entries)
+	line := ""
+	for line == "" {
+		if !r.scanner.Scan() {
+			// The file is EOF or the scan failed
+			r.err = r.scanner.Err()
+			return false
+		}
+		line = r.scanner.Text()
 	}
-	if r.scanner.Err() != nil {
-		// The scan failed (or EOF)
+
+	if !r.restart.MatchString(line) {
+		r.err = fmt.Errorf("Entry does not start with an ID line (%s): %w", line, ErrMalformedEntry)
 		return false
 	}
 
-	line := r.scanner.Text()
-	if r.restart.MatchString(line) {
-		r.data = append(r.data, line)
-		for r.scanner.Scan() {
-			// We suppose no error if line 1 is ok
-			line = r.scanner.Text()
-			if r.reend.MatchString(line) {
-				return true
-			}
-			// Do not append the // line!
-			r.data = append(r.data, line)
+	r.data = append(r.data, line)
+	for r.scanner.Scan() {
+		line = r.scanner.Text()
+		if r.reend.MatchString(line) {
+			return true
 		}
+		// Do not append the // line!
+		r.data = append(r.data, line)
 	}
 
 	// Missing entry end => return false
+	r.err = r.scanner.Err()
+	if r.err == nil {
+		r.err = fmt.Errorf("Missing entry end (%s): %w", r.data[0], ErrMalformedEntry)
+	}
 	return false
 }
 
-func (r *Reader) Close() {
-	r.closer.Close()
+func (r *Reader) Close() error {
+	return r.closer.Close()
 }
 
-func (r *Reader) PanicOnError() {
-	if r.err != nil {
-		panic(r.err)
-	}
+// Return the error that stopped the reading (nil at EOF)
+func (r *Reader) Err() error {
+	return r.err
 }
 
 func (r *Reader) GetData() *[]string {
@@ -133,30 +114,22 @@ func (r *Reader) GetData() *[]string {
 	subset selection and do not extract the complete
 	data from the entry.
 */
-func (r *Reader) LightParse() *Entry {
+func (r *Reader) LightParse() (*Entry, error) {
 	if len(r.data) == 0 {
-		panic("No data read. You must call Next() method first.")
+		return nil, ErrNoData
 	}
 
 	// Initialize the new entry
 	var entry Entry
 
 	// Split data by line types into a map
-	mdata := make(map[string]string)
-	for _, line := range r.data {
-		key := line[0:2]
-		mdata[key] += line[5:]
-	}
+	mdata := r.splitData()
 
 	// Retrieve the length of the protein
-	le := regexp.MustCompile(`(\d+) AA`).FindStringSubmatch(mdata["ID"])
-	if len(le) != 2 {
-		panic(fmt.Sprintf("Failed to retrieve the length of the protein (%s).", mdata["ID"]))
-	}
 	var err error
-	entry.Length, err = strconv.Atoi(le[1])
+	entry.Length, err = parseLength(mdata["ID"])
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	// Organisme and phylum
@@ -164,101 +137,50 @@ func (r *Reader) LightParse() *Entry {
 	entry.Phylum = mdata["OC"]
 
 	// Entry evidence level
-	entry.Evidence = mdata["PE"][0:1]
+	entry.Evidence, err = parseEvidence(mdata["PE"])
+	if err != nil {
+		return nil, err
+	}
 
-	return &entry
+	return &entry, nil
 }
 
-func (r *Reader) Parse() *Entry {
+// Parse the complete entry (see Entry)
+func (r *Reader) Parse() (*Entry, error) {
 	if len(r.data) == 0 {
-		panic("No data read. You must call Next() method first.")
+		return nil, ErrNoData
 	}
+	return parseEntry(r.data)
+}
 
-	// Initialize the new entry
-	var entry Entry
-
-	// Split data by line types into a map
+// Split data by line types into a map
+func (r *Reader) splitData() map[string]string {
 	mdata := make(map[string]string)
 	for _, line := range r.data {
+		if len(line) < 5 {
+			continue
+		}
 		key := line[0:2]
 		mdata[key] += line[5:]
 	}
+	return mdata
+}
 
-	// Retrieve the length of the protein
-	le := regexp.MustCompile(`(\d+) AA`).FindStringSubmatch(mdata["ID"])
+// Retrieve the length of the protein from the ID line
+func parseLength(id string) (int, error) {
+	le := regexp.MustCompile(`(\d+) AA`).FindStringSubmatch(id)
 	if len(le) != 2 {
-		panic(fmt.Sprintf("Failed to retrieve the length of the protein (%s).", mdata["ID"]))
-	}
-	var err error
-	entry.Length, err = strconv.Atoi(le[1])
-	if err != nil {
-		panic(err)
-	}
-
-	// Retrieve accession number
-	// NOTE: For sake of simplicify, only the first accession will be kept
-	ac := r.reac.Split(mdata["AC"], -1)
-	entry.Access = ac[0]
-
-	// Get information from CC lines
-	if mdata["CC"] != "" {
-		// Delete the copyright that can be merged with the function
-		tmpCC := strings.Split(mdata["CC"], "---------")
-
-		// Split by "-!- "
-		cc := strings.Split(tmpCC[0], "-!- ")
-
-		// Scan each cc type
-	CCVAL:
-		for _, ccv := range cc {
-			// Look for FUNCTION
-			if len(ccv) > 5 {
-				if ccv[0:5] == "FUNCT" {
-					ccv = commentFunctionCleanup(ccv)
-					entry.Function = ccv
-					break CCVAL
-				}
-			}
-		}
-	}
-
-	// Retieve gene name and locus tag
-	if mdata["GN"] != "" {
-		// Retrieve the gene name (can be null)
-		gn := r.regn.FindStringSubmatch(mdata["GN"])
-		if gn != nil {
-			entry.Name = gn[1]
-		}
-
-		// Retrieve the locus tag (can be null)
-		lt := r.relt.FindStringSubmatch(mdata["GN"])
-		if lt != nil {
-			entry.Locus = lt[1]
-		}
+		return 0, fmt.Errorf("Failed to retrieve the length of the protein (%s): %w", id, ErrMalformedEntry)
 	}
+	return strconv.Atoi(le[1])
+}
 
-	// Retrieve the functional annotation
-	// NOTE: we suppose that all DE entries start with "RecName: Full="
-	if mdata["DE"] != "" {
-		de := r.resc.Split(mdata["DE"], 2)
-		if r.rede.MatchString(de[0]) {
-			// Delete useless accession numbers (ex. {ECO:XXXX})
-			tmpDesc := strings.Split(de[0][14:], " {")
-			entry.Desc = tmpDesc[0]
-		}
+// Retrieve the evidence level from the PE line
+func parseEvidence(pe string) (string, error) {
+	if pe == "" {
+		return "", fmt.Errorf("Missing protein existence (PE) line: %w", ErrMalformedEntry)
 	}
-
-	// Organisme and phylum
-	entry.Organism = mdata["OS"]
-	entry.Phylum = mdata["OC"]
-
-	// Protein sequence
-	entry.Sequence = r.rese.ReplaceAllString(mdata["  "], "")
-
-	// Entry evidence level
-	entry.Evidence = mdata["PE"][0:1]
-
-	return &entry
+	return pe[0:1], nil
 }
 
 func commentFunctionCleanup(ccv string) string {
@@ -274,7 +196,7 @@ func commentFunctionCleanup(ccv string) string {
 	// Split sentences
 	sen := strings.Split(ccv, ". ")
 	for i := range sen {
-		if regexp.MustCompile(`[A-Z][a-z ]`).MatchString(sen[i][0:2]) {
+		if len(sen[i]) > 1 && regexp.MustCompile(`[A-Z][a-z ]`).MatchString(sen[i][0:2]) {
 			tmp := []rune(sen[i])
 			tmp[0] = unicode.ToLower(tmp[0])
 			sen[i] = string(tmp)
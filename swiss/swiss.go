@@ -1,5 +1,13 @@
 package swiss
 
+import "errors"
+
+// Errors returned by the readers and writers
+var (
+	ErrMalformedEntry = errors.New("malformed entry")
+	ErrNoData         = errors.New("no data read, Next() must be called first")
+)
+
 /*
 	Shared interface in the module
 */
@@ -0,0 +1,172 @@
+package swiss
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Update the golden files.")
+
+// Parse all the entries of a file
+func parseTestFile(t *testing.T, file string) []*Entry {
+	swr, err := NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer swr.Close()
+
+	entries := make([]*Entry, 0)
+	for swr.Next() {
+		e, err := swr.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if swr.Err() != nil {
+		t.Fatal(swr.Err())
+	}
+	return entries
+}
+
+// The test files contain Swiss-Prot and TrEMBL entries adapted from
+// UniProtKB (some sequences and line blocks are shortened), the parsed
+// entries are compared to the golden files (go test -update to rewrite
+// them)
+func TestParseGolden(t *testing.T) {
+	for _, name := range []string{"swissprot", "trembl"} {
+		entries := parseTestFile(t, "testdata/"+name+".dat")
+		got, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, '\n')
+
+		golden := "testdata/" + name + ".golden.json"
+		if *update {
+			err = ioutil.WriteFile(golden, got, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		exp, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, exp) {
+			t.Errorf("The parsed entries of %s differ from %s.", name, golden)
+		}
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	entries := parseTestFile(t, "testdata/swissprot.dat")
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, found %d.", len(entries))
+	}
+
+	// Values used to build the reference DBs
+	e := entries[0]
+	if e.Access != "P00044" || e.Name != "CYC1" || e.Locus != "YJR048W" || e.Desc != "Cytochrome c iso-1" || e.Evidence != "1" {
+		t.Errorf("Unexpected main values: %s %s %s %s %s.", e.Access, e.Name, e.Locus, e.Desc, e.Evidence)
+	}
+	if e.Function != "electron carrier protein; the oxidized form of the cytochrome c heme group can accept an electron from the heme group of the cytochrome c1 subunit of cytochrome reductase" {
+		t.Errorf("Unexpected function: %s.", e.Function)
+	}
+	if len(e.Sequence) != e.Length {
+		t.Errorf("The sequence length (%d) differs from the ID line (%d).", len(e.Sequence), e.Length)
+	}
+
+	// Components and flags
+	e = entries[1]
+	if len(e.Contains) != 2 || e.Contains[1].RecName.Full != "Insulin A chain" || len(e.Flags) != 1 || e.Flags[0] != "Precursor" {
+		t.Errorf("Unexpected components or flags: %v %v.", e.Contains, e.Flags)
+	}
+	if refs := e.GetCrossRefs("CCDS"); len(refs) != 1 || refs[0].Isoform != "P01308-1" {
+		t.Errorf("Unexpected CCDS cross-references: %v.", refs)
+	}
+
+	// Names with EC numbers and evidence tags
+	e = entries[2]
+	if e.Desc != "Enolase" || len(e.Protein.RecName.EC) != 1 || e.Protein.RecName.EC[0] != "4.2.1.11" {
+		t.Errorf("Unexpected recommended name: %v.", e.Protein.RecName)
+	}
+	if len(e.Protein.AltNames) != 2 || e.Protein.AltNames[1].Short[0] != "2-PGA dehydratase" {
+		t.Errorf("Unexpected alternative names: %v.", e.Protein.AltNames)
+	}
+	if p := e.GetComments("PATHWAY"); len(p) != 1 || p[0] != "Carbohydrate degradation; glycolysis; pyruvate from D-glyceraldehyde 3-phosphate: step 4/5." {
+		t.Errorf("Unexpected pathway: %v.", p)
+	}
+}
+
+func TestParseSubName(t *testing.T) {
+	entries := parseTestFile(t, "testdata/trembl.dat")
+	e := entries[0]
+	if e.Desc != "Protein kinase" || e.DescType != DESC_SUBNAME || e.Reviewed {
+		t.Errorf("Expected the submitted name of the TrEMBL entry, found %q (%s).", e.Desc, e.DescType)
+	}
+	e = parseTestFile(t, "testdata/swissprot.dat")[0]
+	if e.DescType != DESC_RECNAME {
+		t.Errorf("Expected the recommended name of the Swiss-Prot entry, found %s.", e.DescType)
+	}
+}
+
+func TestParseOntology(t *testing.T) {
+	e := parseTestFile(t, "testdata/swissprot.dat")[2]
+	gt := e.GoTerms()
+	if len(gt) != 2 || gt[1] != (GoTerm{"GO:0004634", "F", "phosphopyruvate hydratase activity", "IDA", "EcoCyc"}) {
+		t.Errorf("Unexpected GO terms: %v.", gt)
+	}
+	if ec := e.ECNumbers(); len(ec) != 1 || ec[0] != "4.2.1.11" {
+		t.Errorf("Unexpected EC numbers: %v.", ec)
+	}
+	if pw := e.Pathways(); len(pw) != 1 || pw[0] != "Carbohydrate degradation; glycolysis; pyruvate from D-glyceraldehyde 3-phosphate: step 4/5" {
+		t.Errorf("Unexpected pathways: %v.", pw)
+	}
+}
+
+func TestParseMalformedReference(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/swissprot.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(data), "\n")
+	end := 0
+	for lines[end] != "//" {
+		end++
+	}
+	entry := lines[:end]
+
+	// Drop the lines of a type from the first reference (or the RN line
+	// of the first reference)
+	drop := func(key string) []string {
+		out := make([]string, 0, len(entry))
+		ref := 0
+		for _, l := range entry {
+			if strings.HasPrefix(l, "RN") {
+				ref++
+			}
+			if ref == 1 && strings.HasPrefix(l, key) {
+				continue
+			}
+			out = append(out, l)
+		}
+		return out
+	}
+
+	_, err = parseEntry(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"RN", "RA", "RL"} {
+		_, err = parseEntry(drop(key))
+		if !errors.Is(err, ErrMalformedEntry) {
+			t.Errorf("Expected ErrMalformedEntry without the %s line of the first reference, got %v.", key, err)
+		}
+	}
+}
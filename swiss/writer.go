@@ -11,13 +11,12 @@ import (
 type Writer struct {
 	closer FileCloser
 	writer FileWriter
-	err    error
 }
 
-func NewWriter(file string) *Writer {
+func NewWriter(file string) (*Writer, error) {
 	f, err := os.Create(file)
 	if err != nil {
-		return &Writer{err: err}
+		return nil, err
 	}
 
 	// If the provided file has a 'gz' extention, then compress
@@ -27,35 +26,40 @@ func NewWriter(file string) *Writer {
 		return &Writer{
 			closer: fgz,
 			writer: fgz,
-		}
+		}, nil
 	} else {
 		return &Writer{
 			closer: f,
 			writer: bufio.NewWriter(f),
-		}
+		}, nil
 	}
 }
 
-func (w *Writer) Close() {
+func (w *Writer) Close() error {
 	// Flush before closing
-	w.err = w.writer.Flush()
-	w.PanicOnError()
-	w.closer.Close()
-}
-
-func (w *Writer) PanicOnError() {
-	if w.err != nil {
-		panic(w.err)
+	err := w.writer.Flush()
+	if err != nil {
+		w.closer.Close()
+		return err
 	}
+	return w.closer.Close()
 }
 
-func (w *Writer) WriteStrings(s *[]string) {
+func (w *Writer) WriteStrings(s *[]string) error {
 	for i := range *s {
-		_, w.err = w.writer.Write([]byte((*s)[i]))
-		_, w.err = w.writer.Write([]byte{'\n'})
+		_, err := w.writer.Write([]byte((*s)[i]))
+		if err != nil {
+			return err
+		}
+		_, err = w.writer.Write([]byte{'\n'})
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (w *Writer) WriteEntryEnd() {
-	_, w.err = w.writer.Write([]byte{'/', '/', '\n'})
+func (w *Writer) WriteEntryEnd() error {
+	_, err := w.writer.Write([]byte{'/', '/', '\n'})
+	return err
 }